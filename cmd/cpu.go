@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,11 +10,18 @@ import (
 	"github.com/USA-RedDragon/configulator"
 	"github.com/USA-RedDragon/go-gb/internal/cartridge"
 	"github.com/USA-RedDragon/go-gb/internal/config"
-	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/link"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
 	"github.com/lmittmann/tint"
 	"github.com/spf13/cobra"
 )
 
+var (
+	ErrTestFailed       = errors.New("test ROM reported failure")
+	ErrExitOnResultLink = errors.New("--exit-on-result cannot be used with a link cable")
+)
+
 func newCPUCommand(version, commit string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "cpu",
@@ -26,10 +34,11 @@ func newCPUCommand(version, commit string) *cobra.Command {
 		SilenceErrors:     true,
 		DisableAutoGenTag: true,
 	}
+	cmd.Flags().Bool("exit-on-result", false, "Exit once the serial output reports \"Passed\" or \"Failed\", with a failure code for the latter.")
 	return cmd
 }
 
-func runCPU(cmd *cobra.Command, _ []string) error {
+func runCPU(cmd *cobra.Command, _ []string) (err error) {
 	ctx := cmd.Context()
 	fmt.Printf("go-gb - %s (%s)\n", cmd.Annotations["version"], cmd.Annotations["commit"])
 
@@ -56,6 +65,11 @@ func runCPU(cmd *cobra.Command, _ []string) error {
 	}
 	slog.SetDefault(logger)
 
+	exitOnResult, err := cmd.Flags().GetBool("exit-on-result")
+	if err != nil {
+		return fmt.Errorf("failed to get exit-on-result flag: %w", err)
+	}
+
 	var cart *cartridge.Cartridge
 	if cfg.ROM != "" {
 		cart, err = cartridge.NewCartridge(cfg.ROM)
@@ -64,15 +78,75 @@ func runCPU(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	cpu := cpu.NewSM83(cfg, cart)
+	gb, err := machine.New(cfg, cart)
+	if err != nil {
+		return err
+	}
+
+	closeTrace, err := startTrace(cfg, gb)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeTrace(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	writeProfile, err := startProfile(cfg, gb)
+	if err != nil {
+		return err
+	}
+
+	cable, err := link.Open(cfg.LinkListen, cfg.LinkConnect)
+	if err != nil {
+		return err
+	}
+	if cable != nil {
+		if exitOnResult {
+			cable.Close()
+			return ErrExitOnResultLink
+		}
+		defer cable.Close()
+		gb.Serial.SetPeer(cable)
+	}
+
+	var capture *serial.Capture
+	switch {
+	case cfg.SerialOutput != "":
+		capture, err = serial.OpenCapture(cfg.SerialOutput)
+		if err != nil {
+			return err
+		}
+		defer capture.Close()
+	case exitOnResult:
+		capture = serial.NewCapture(nil)
+	}
+	passed := true
+	if capture != nil {
+		if exitOnResult {
+			capture.OnResult = func(result bool) {
+				passed = result
+				gb.Quit()
+			}
+		}
+		gb.Serial.SetPeer(capture)
+	}
+
 	go func() {
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt)
 		for range ch {
 			fmt.Println("Exiting")
-			cpu.Quit()
+			gb.Quit()
 		}
 	}()
-	cpu.Run()
+	gb.Run()
+	if err := writeProfile(); err != nil {
+		return err
+	}
+	if !passed {
+		return ErrTestFailed
+	}
 	return nil
 }
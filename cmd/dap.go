@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/dap"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+func newDAPCommand(version, commit string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dap",
+		Short: "Serve the Debug Adapter Protocol for debugging from an editor",
+		Long: "Speaks the Debug Adapter Protocol over stdin and stdout, or to each client connecting to --listen in turn. " +
+			"The client launches a ROM with a program path, and optionally symbols, sourceRoot and stopOnEntry.",
+		Version: fmt.Sprintf("%s - %s", version, commit),
+		Annotations: map[string]string{
+			"version": version,
+			"commit":  commit,
+		},
+		RunE:              runDAP,
+		SilenceErrors:     true,
+		DisableAutoGenTag: true,
+	}
+	cmd.Flags().String("listen", "", "Address to accept clients on, e.g. :4711, instead of using stdin and stdout.")
+	return cmd
+}
+
+func runDAP(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	c, err := configulator.FromContext[config.Config](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get config from context")
+	}
+
+	cfg, err := c.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// The protocol may use stdout, so log to stderr
+	var logger *slog.Logger
+	switch cfg.LogLevel {
+	case config.LogLevelDebug:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelDebug}))
+	case config.LogLevelInfo:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelInfo}))
+	case config.LogLevelWarn:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelWarn}))
+	case config.LogLevelError:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelError}))
+	}
+	slog.SetDefault(logger)
+
+	listen, err := cmd.Flags().GetString("listen")
+	if err != nil {
+		return fmt.Errorf("failed to get listen flag: %w", err)
+	}
+	if listen == "" {
+		return dap.New(cfg, os.Stdin, os.Stdout).Serve()
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	defer listener.Close()
+	slog.Info("Waiting for a debug adapter client", "address", listener.Addr().String())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("failed to accept client: %w", err)
+		}
+		slog.Info("Debug adapter client connected", "address", conn.RemoteAddr().String())
+		if err := dap.New(cfg, conn, conn).Serve(); err != nil {
+			slog.Warn("Debug adapter session failed", "error", err)
+		}
+		conn.Close()
+	}
+}
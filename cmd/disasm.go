@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+var ErrDisasmNoROM = errors.New("--rom is required")
+
+func newDisasmCommand(version, commit string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "disasm",
+		Short: "Disassemble a ROM into RGBDS assembly",
+		Long: "Follows the code reachable from the ROM's entry point, RST vectors and interrupt vectors, " +
+			"labelling the targets of jumps and calls, and writes everything else as data. " +
+			"Labels from the ROM's .sym file, or --symbols, replace the generated names.",
+		Version: fmt.Sprintf("%s - %s", version, commit),
+		Annotations: map[string]string{
+			"version": version,
+			"commit":  commit,
+		},
+		RunE:              runDisasm,
+		SilenceErrors:     true,
+		DisableAutoGenTag: true,
+	}
+	cmd.Flags().StringP("output", "o", "-", "Path to write the assembly to, or - for stdout.")
+	return cmd
+}
+
+func runDisasm(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+
+	c, err := configulator.FromContext[config.Config](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get config from context")
+	}
+
+	cfg, err := c.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// The assembly may go to stdout, so log to stderr
+	var logger *slog.Logger
+	switch cfg.LogLevel {
+	case config.LogLevelDebug:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelDebug}))
+	case config.LogLevelInfo:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelInfo}))
+	case config.LogLevelWarn:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelWarn}))
+	case config.LogLevelError:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelError}))
+	}
+	slog.SetDefault(logger)
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("failed to get output flag: %w", err)
+	}
+
+	if cfg.ROM == "" {
+		return ErrDisasmNoROM
+	}
+	rom, err := os.ReadFile(cfg.ROM)
+	if err != nil {
+		return fmt.Errorf("failed to read ROM: %w", err)
+	}
+
+	table, err := symbols.Load(cfg.ROM, cfg.Symbols)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if output != "-" {
+		out, err = os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer out.Close()
+	}
+	if err := disasm.Disassemble(rom, table).Write(out); err != nil {
+		return fmt.Errorf("failed to write assembly: %w", err)
+	}
+	if output != "-" {
+		slog.Info("Wrote disassembly", "path", output)
+		return out.Close()
+	}
+	return nil
+}
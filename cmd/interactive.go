@@ -4,13 +4,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"strconv"
-	"strings"
 
 	"github.com/USA-RedDragon/configulator"
 	"github.com/USA-RedDragon/go-gb/internal/cartridge"
 	"github.com/USA-RedDragon/go-gb/internal/config"
-	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/debugger"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
 	"github.com/lmittmann/tint"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +18,7 @@ import (
 func newInteractiveCommand(version, commit string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "interactive",
+		Short:   "Debug a ROM from the command line",
 		Version: fmt.Sprintf("%s - %s", version, commit),
 		Annotations: map[string]string{
 			"version": version,
@@ -30,7 +31,7 @@ func newInteractiveCommand(version, commit string) *cobra.Command {
 	return cmd
 }
 
-func runInteractive(cmd *cobra.Command, _ []string) error {
+func runInteractive(cmd *cobra.Command, _ []string) (err error) {
 	ctx := cmd.Context()
 	fmt.Printf("go-gb - %s (%s)\n", cmd.Annotations["version"], cmd.Annotations["commit"])
 
@@ -65,43 +66,35 @@ func runInteractive(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	cpu := cpu.NewSM83(cfg, cart)
-	// Wait for the user to hit Enter, run the CPU step and repeat until control-C is pressed
-	fmt.Println("Interactive mode started. Press Enter to step through the CPU instructions. Type exit or quit to exit.")
-	for {
-		var input string
-		_, err = fmt.Scanln(&input) // Wait for user input
-		if err != nil {
-			if err.Error() != "unexpected newline" {
-				return fmt.Errorf("failed to read input: %w", err)
-			}
-		}
-		input = strings.TrimSpace(input)
-		if input == "exit" || input == "quit" {
-			break
-		}
-		if strings.HasPrefix(input, "0x") {
-			// Convert hex address to integer
-			addr, err := strconv.ParseUint(input[2:], 16, 16)
-			if err != nil {
-				fmt.Printf("Invalid address: %s\n", input)
-				continue
-			}
-			// Run Step until the PC reaches the specified address
-			for cpu.GetPC() != uint16(addr) {
-				cpu.Step() // Execute one CPU instruction
-			}
-		}
-		if steps, err := strconv.Atoi(input); err == nil {
-			// If input is a number, run Step that many times
-			for range steps {
-				cpu.Step() // Execute one CPU instruction
-				slog.Debug("CPU Step executed")
-			}
-			continue
+	table, err := symbols.Load(cfg.ROM, cfg.Symbols)
+	if err != nil {
+		return err
+	}
+
+	gb, err := machine.New(cfg, cart)
+	if err != nil {
+		return err
+	}
+	gb.SetSymbols(table)
+
+	closeTrace, err := startTrace(cfg, gb)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeTrace(); closeErr != nil && err == nil {
+			err = closeErr
 		}
-		cpu.Step() // Execute one CPU instruction
-		slog.Debug("CPU Step executed")
+	}()
+	writeProfile, err := startProfile(cfg, gb)
+	if err != nil {
+		return err
+	}
+	if err := debugger.New(gb, os.Stdout).Run(os.Stdin); err != nil {
+		return err
+	}
+	if err := writeProfile(); err != nil {
+		return err
 	}
 	fmt.Println("Exiting interactive mode.")
 	return nil
@@ -11,6 +11,10 @@ import (
 	"github.com/USA-RedDragon/go-gb/internal/cartridge"
 	"github.com/USA-RedDragon/go-gb/internal/config"
 	"github.com/USA-RedDragon/go-gb/internal/emulator"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/profile"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+	"github.com/USA-RedDragon/go-gb/internal/trace"
 	ebiten "github.com/hajimehoshi/ebiten/v2"
 	"github.com/lmittmann/tint"
 	"github.com/spf13/cobra"
@@ -30,6 +34,10 @@ func NewCommand(version, commit string) *cobra.Command {
 	}
 	cmd.AddCommand(newInteractiveCommand(version, commit))
 	cmd.AddCommand(newCPUCommand(version, commit))
+	cmd.AddCommand(newRunCommand(version, commit))
+	cmd.AddCommand(newTestCommand(version, commit))
+	cmd.AddCommand(newDisasmCommand(version, commit))
+	cmd.AddCommand(newDAPCommand(version, commit))
 	return cmd
 }
 
@@ -60,15 +68,15 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 	}
 	slog.SetDefault(logger)
 
-	var cart *cartridge.Cartridge
-	if cfg.ROM != "" {
-		cart, err = cartridge.NewCartridge(cfg.ROM)
-		if err != nil {
-			return fmt.Errorf("failed to load cartridge: %w", err)
-		}
+	rom, cart, err := loadROM(cfg)
+	if err != nil {
+		return err
 	}
 
-	emu := emulator.New(cfg, cart)
+	emu, err := emulator.New(cfg, rom)
+	if err != nil {
+		return fmt.Errorf("failed to create emulator: %w", err)
+	}
 	go func() {
 		ch := make(chan os.Signal, 1)
 		signal.Notify(ch, os.Interrupt)
@@ -78,6 +86,75 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 		}
 	}()
 
+	setupWindow(cfg, cart)
+
+	if err := ebiten.RunGame(emu); err != nil {
+		return err
+	}
+	return emu.Close()
+}
+
+// loadROM reads the ROM set in cfg, if any, and parses its header.
+func loadROM(cfg *config.Config) ([]byte, *cartridge.Cartridge, error) {
+	if cfg.ROM == "" {
+		return nil, nil, nil
+	}
+	rom, err := os.ReadFile(cfg.ROM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read ROM: %w", err)
+	}
+	cart, err := cartridge.ParseCartridge(rom)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load cartridge: %w", err)
+	}
+	return rom, cart, nil
+}
+
+// startProfile starts profiling gb if cfg sets a profile path, loading the
+// ROM's symbols to name functions after if gb has none. It returns a function
+// that writes the profile out, or does nothing if profiling is off.
+func startProfile(cfg *config.Config, gb *machine.Machine) (func() error, error) {
+	if cfg.Profile == "" {
+		return func() error { return nil }, nil
+	}
+	if gb.Symbols() == nil {
+		table, err := symbols.Load(cfg.ROM, cfg.Symbols)
+		if err != nil {
+			return nil, err
+		}
+		gb.SetSymbols(table)
+	}
+	profiler := profile.New(gb.Symbols())
+	gb.SetProfiler(profiler)
+	return func() error {
+		gb.SetProfiler(nil)
+		if err := profiler.WriteFile(cfg.Profile); err != nil {
+			return err
+		}
+		slog.Info("Wrote profile", "path", cfg.Profile)
+		return nil
+	}, nil
+}
+
+// startTrace traces the instructions gb runs to cfg.Trace, if set. The
+// returned function stops tracing and closes the trace, which flushes it, so
+// its error matters even after a successful run.
+func startTrace(cfg *config.Config, gb *machine.Machine) (func() error, error) {
+	tracer, err := trace.Open(cfg.Trace, cfg.TraceStart, cfg.TraceStop)
+	if err != nil {
+		return nil, err
+	}
+	if tracer == nil {
+		return func() error { return nil }, nil
+	}
+	gb.SetTracer(tracer)
+	return func() error {
+		gb.SetTracer(nil)
+		return tracer.Close()
+	}, nil
+}
+
+func setupWindow(cfg *config.Config, cart *cartridge.Cartridge) {
 	ebiten.SetWindowSize(int(cfg.Scale*160), int(cfg.Scale*144))
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetFullscreen(cfg.Fullscreen)
@@ -90,6 +167,4 @@ func runRoot(cmd *cobra.Command, _ []string) error {
 	default:
 		ebiten.SetWindowTitle("go-gb")
 	}
-
-	return ebiten.RunGame(emu)
 }
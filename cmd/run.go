@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"os"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/emulator"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	"github.com/USA-RedDragon/go-gb/internal/link"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/ppu"
+	"github.com/USA-RedDragon/go-gb/internal/printer"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+	ebiten "github.com/hajimehoshi/ebiten/v2"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrMovieAndScript    = errors.New("--play-movie and --script cannot be used together")
+	ErrInvalidFrames     = errors.New("--frames cannot be negative")
+	ErrNoFrames          = errors.New("--frames must be positive without --window, --play-movie or --script")
+	ErrFrameHashMismatch = errors.New("frame hash does not match")
+	ErrRAMHashMismatch   = errors.New("RAM hash does not match")
+)
+
+func newRunCommand(version, commit string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "run",
+		Short:   "Run a ROM for a number of frames and print hashes of the final frame and RAM",
+		Version: fmt.Sprintf("%s - %s", version, commit),
+		Annotations: map[string]string{
+			"version": version,
+			"commit":  commit,
+		},
+		RunE:              runRun,
+		SilenceErrors:     true,
+		DisableAutoGenTag: true,
+	}
+	cmd.Flags().Int("frames", 0, "Number of frames to run. Defaults to the length of the played movie or input script, or with --window to running until the window is closed.")
+	cmd.Flags().String("script", "", "Path of an input script to drive the joypad with.")
+	cmd.Flags().Bool("window", false, "Watch the run in a window at normal speed instead of running it as fast as possible without one.")
+	cmd.Flags().String("screenshot", "", "Path to write a PNG of the final frame to.")
+	cmd.Flags().String("expect-frame-hash", "", "Fail unless the final frame has this SHA-256.")
+	cmd.Flags().String("expect-ram-hash", "", "Fail unless WRAM+HRAM have this SHA-256.")
+	return cmd
+}
+
+func runRun(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	fmt.Printf("go-gb - %s (%s)\n", cmd.Annotations["version"], cmd.Annotations["commit"])
+
+	c, err := configulator.FromContext[config.Config](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get config from context")
+	}
+
+	cfg, err := c.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var logger *slog.Logger
+	switch cfg.LogLevel {
+	case config.LogLevelDebug:
+		logger = slog.New(tint.NewHandler(os.Stdout, &tint.Options{Level: slog.LevelDebug}))
+	case config.LogLevelInfo:
+		logger = slog.New(tint.NewHandler(os.Stdout, &tint.Options{Level: slog.LevelInfo}))
+	case config.LogLevelWarn:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelWarn}))
+	case config.LogLevelError:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelError}))
+	}
+	slog.SetDefault(logger)
+
+	frames, err := cmd.Flags().GetInt("frames")
+	if err != nil {
+		return fmt.Errorf("failed to get frames flag: %w", err)
+	}
+	if frames < 0 {
+		return ErrInvalidFrames
+	}
+	script, err := cmd.Flags().GetString("script")
+	if err != nil {
+		return fmt.Errorf("failed to get script flag: %w", err)
+	}
+	if script != "" && cfg.PlayMovie != "" {
+		return ErrMovieAndScript
+	}
+	window, err := cmd.Flags().GetBool("window")
+	if err != nil {
+		return fmt.Errorf("failed to get window flag: %w", err)
+	}
+	screenshot, err := cmd.Flags().GetString("screenshot")
+	if err != nil {
+		return fmt.Errorf("failed to get screenshot flag: %w", err)
+	}
+	expectFrameHash, err := cmd.Flags().GetString("expect-frame-hash")
+	if err != nil {
+		return fmt.Errorf("failed to get expect-frame-hash flag: %w", err)
+	}
+	expectRAMHash, err := cmd.Flags().GetString("expect-ram-hash")
+	if err != nil {
+		return fmt.Errorf("failed to get expect-ram-hash flag: %w", err)
+	}
+
+	rom, cart, err := loadROM(cfg)
+	if err != nil {
+		return err
+	}
+
+	var movie, scriptMovie *input.Movie
+	if cfg.PlayMovie != "" {
+		movie, err = readMovie(cfg.PlayMovie)
+		if err != nil {
+			return err
+		}
+		if frames == 0 {
+			frames = len(movie.Frames)
+		}
+	}
+	if script != "" {
+		scriptMovie, err = readScript(script)
+		if err != nil {
+			return err
+		}
+		if frames == 0 {
+			frames = len(scriptMovie.Frames)
+		}
+	}
+	if frames == 0 && !window {
+		// Nothing would run, and the hashes of the power-on state would
+		// pass for a result
+		return ErrNoFrames
+	}
+
+	var (
+		frame [consts.FrameBufferSize]byte
+		ram   []byte
+	)
+	if window {
+		frame, ram, err = runWindowed(cfg, rom, cart, scriptMovie, frames)
+	} else {
+		frame, ram, err = runHeadless(cfg, cart, movie, scriptMovie, frames)
+	}
+	if err != nil {
+		return err
+	}
+
+	if screenshot != "" {
+		if err := writeScreenshot(screenshot, frame); err != nil {
+			return err
+		}
+	}
+
+	frameHash := sha256.Sum256(frame[:])
+	ramHash := sha256.Sum256(ram)
+
+	frameHashHex := hex.EncodeToString(frameHash[:])
+	ramHashHex := hex.EncodeToString(ramHash[:])
+	fmt.Printf("frames: %d\nframe: %s\nram: %s\n", frames, frameHashHex, ramHashHex)
+
+	if expectFrameHash != "" && expectFrameHash != frameHashHex {
+		return fmt.Errorf("%w: got %s, want %s", ErrFrameHashMismatch, frameHashHex, expectFrameHash)
+	}
+	if expectRAMHash != "" && expectRAMHash != ramHashHex {
+		return fmt.Errorf("%w: got %s, want %s", ErrRAMHashMismatch, ramHashHex, expectRAMHash)
+	}
+	return nil
+}
+
+// runHeadless runs the machine for the given number of frames as fast as
+// possible, without a window. It returns the final frame and the contents of
+// WRAM followed by HRAM.
+func runHeadless(cfg *config.Config, cart *cartridge.Cartridge, movie, script *input.Movie, frames int) (frame [consts.FrameBufferSize]byte, ram []byte, err error) {
+	gb, err := machine.New(cfg, cart)
+	if err != nil {
+		return frame, nil, err
+	}
+
+	closeTrace, err := startTrace(cfg, gb)
+	if err != nil {
+		return frame, nil, err
+	}
+	defer func() {
+		if closeErr := closeTrace(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	writeProfile, err := startProfile(cfg, gb)
+	if err != nil {
+		return frame, nil, err
+	}
+
+	if cfg.SerialOutput != "" {
+		capture, err := serial.OpenCapture(cfg.SerialOutput)
+		if err != nil {
+			return frame, nil, err
+		}
+		defer capture.Close()
+		gb.Serial.SetPeer(capture)
+	}
+
+	cable, err := link.Open(cfg.LinkListen, cfg.LinkConnect)
+	if err != nil {
+		return frame, nil, err
+	}
+	if cable != nil {
+		defer cable.Close()
+		gb.Serial.SetPeer(cable)
+	}
+
+	if cfg.PrinterOutput != "" {
+		gb.Serial.SetPeer(printer.NewPrinter(cfg.PrinterOutput))
+	}
+
+	// Recording resets the machine, so it has to start before playback
+	var recording *input.Movie
+	if cfg.RecordMovie != "" {
+		recording = gb.RecordMovie()
+	}
+	if movie != nil {
+		if err := gb.PlayMovie(movie); err != nil {
+			return frame, nil, fmt.Errorf("failed to play movie: %w", err)
+		}
+		if recording != nil {
+			recording.Header.StartState = movie.Header.StartState
+		}
+	}
+	if script != nil {
+		gb.Input.Play(script)
+	}
+
+	for range frames {
+		frame = gb.RunUntilFrame()
+	}
+	if recording != nil {
+		gb.Input.StopRecording()
+		if err := writeMovie(cfg.RecordMovie, recording); err != nil {
+			return frame, nil, err
+		}
+	}
+	if err := writeProfile(); err != nil {
+		return frame, nil, err
+	}
+	return frame, append(gb.RAM[:], gb.HRAM[:]...), nil
+}
+
+// runWindowed runs the machine in a window at normal speed until it has
+// emulated the given number of frames, or until the window is closed if
+// frames is 0. It returns the same as runHeadless.
+func runWindowed(cfg *config.Config, rom []byte, cart *cartridge.Cartridge, script *input.Movie, frames int) ([consts.FrameBufferSize]byte, []byte, error) {
+	var frame [consts.FrameBufferSize]byte
+	emu, err := emulator.New(cfg, rom)
+	if err != nil {
+		return frame, nil, fmt.Errorf("failed to create emulator: %w", err)
+	}
+	emu.SetFrameLimit(frames)
+	if script != nil {
+		buttons := make([]gameboy.Button, len(script.Frames))
+		for i, frame := range script.Frames {
+			buttons[i] = gameboy.Button(frame)
+		}
+		emu.GameBoy().PlayInput(buttons)
+	}
+
+	setupWindow(cfg, cart)
+	if err := ebiten.RunGame(emu); err != nil {
+		return frame, nil, err
+	}
+	if err := emu.Close(); err != nil {
+		return frame, nil, err
+	}
+	gb := emu.GameBoy()
+	ram := make([]byte, 0, consts.RAMSize+consts.HRAMSize)
+	for addr := 0xC000; addr < 0xC000+consts.RAMSize; addr++ {
+		ram = append(ram, gb.Peek(uint16(addr)))
+	}
+	for addr := 0xFF80; addr < 0xFF80+consts.HRAMSize; addr++ {
+		ram = append(ram, gb.Peek(uint16(addr)))
+	}
+	return emu.LastFrame(), ram, nil
+}
+
+func writeScreenshot(path string, frame [consts.FrameBufferSize]byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create screenshot: %w", err)
+	}
+	if err := png.Encode(file, ppu.FrameImage(frame)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write screenshot: %w", err)
+	}
+	return nil
+}
+
+func readMovie(path string) (*input.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open movie: %w", err)
+	}
+	defer file.Close()
+	movie, err := input.ReadMovie(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read movie: %w", err)
+	}
+	return movie, nil
+}
+
+func writeMovie(path string, movie *input.Movie) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create movie: %w", err)
+	}
+	if err := movie.Write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write movie: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write movie: %w", err)
+	}
+	return nil
+}
+
+func readScript(path string) (*input.Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input script: %w", err)
+	}
+	defer file.Close()
+	movie, err := input.ParseScript(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input script: %w", err)
+	}
+	return movie, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/testrom"
+	"github.com/lmittmann/tint"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrTestROMsFailed = errors.New("test ROMs failed")
+	ErrNoTestROMs     = errors.New("no test ROMs found")
+	ErrInvalidJobs    = errors.New("--jobs must be at least 1")
+)
+
+func newTestCommand(version, commit string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test <dir>",
+		Short: "Run a directory of test ROMs without a window and report which pass",
+		Long: "Runs every .gb file under a directory and decides pass or fail by the convention it reports with: " +
+			"\"Passed\" or \"Failed\" over the serial port (blargg), the Fibonacci registers at LD B,B (mooneye), " +
+			"or, for ROMs with a reference PNG of the same name, the final frame (acid tests).",
+		Version: fmt.Sprintf("%s - %s", version, commit),
+		Annotations: map[string]string{
+			"version": version,
+			"commit":  commit,
+		},
+		Args:              cobra.ExactArgs(1),
+		RunE:              runTest,
+		SilenceErrors:     true,
+		DisableAutoGenTag: true,
+	}
+	cmd.Flags().Int("frames", 3600, "Number of frames each ROM may run for before it fails.")
+	cmd.Flags().String("junit", "", "Path to write a JUnit XML report to.")
+	cmd.Flags().Int("jobs", runtime.NumCPU(), "Number of ROMs to run at once.")
+	return cmd
+}
+
+func runTest(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	fmt.Printf("go-gb - %s (%s)\n", cmd.Annotations["version"], cmd.Annotations["commit"])
+
+	c, err := configulator.FromContext[config.Config](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get config from context")
+	}
+
+	cfg, err := c.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var logger *slog.Logger
+	switch cfg.LogLevel {
+	case config.LogLevelDebug:
+		logger = slog.New(tint.NewHandler(os.Stdout, &tint.Options{Level: slog.LevelDebug}))
+	case config.LogLevelInfo:
+		logger = slog.New(tint.NewHandler(os.Stdout, &tint.Options{Level: slog.LevelInfo}))
+	case config.LogLevelWarn:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelWarn}))
+	case config.LogLevelError:
+		logger = slog.New(tint.NewHandler(os.Stderr, &tint.Options{Level: slog.LevelError}))
+	}
+	slog.SetDefault(logger)
+
+	frames, err := cmd.Flags().GetInt("frames")
+	if err != nil {
+		return fmt.Errorf("failed to get frames flag: %w", err)
+	}
+	junit, err := cmd.Flags().GetString("junit")
+	if err != nil {
+		return fmt.Errorf("failed to get junit flag: %w", err)
+	}
+	jobs, err := cmd.Flags().GetInt("jobs")
+	if err != nil {
+		return fmt.Errorf("failed to get jobs flag: %w", err)
+	}
+	if jobs < 1 {
+		return ErrInvalidJobs
+	}
+
+	roms, err := testrom.Find(args[0])
+	if err != nil {
+		return err
+	}
+	if len(roms) == 0 {
+		return fmt.Errorf("%w in %s", ErrNoTestROMs, args[0])
+	}
+
+	results := make([]testrom.Result, len(roms))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(roms)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = testrom.Run(cfg, roms[i], frames)
+			}
+		}()
+	}
+	for i := range roms {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		name, err := filepath.Rel(args[0], result.Path)
+		if err != nil {
+			name = result.Path
+		}
+		switch {
+		case result.Error:
+			failed++
+			fmt.Printf("ERROR %s: %s\n", name, result.Message)
+		case result.Passed:
+			fmt.Printf("PASS  %s (%s, %d frames)\n", name, result.Convention, result.Frames)
+		default:
+			failed++
+			fmt.Printf("FAIL  %s (%s): %s\n", name, result.Convention, result.Message)
+		}
+	}
+	fmt.Printf("%d/%d passed\n", len(results)-failed, len(results))
+
+	if junit != "" {
+		file, err := os.Create(junit)
+		if err != nil {
+			return fmt.Errorf("failed to create JUnit report: %w", err)
+		}
+		if err := testrom.WriteJUnit(file, "go-gb", results); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrTestROMsFailed, failed, len(results))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cartridge
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"os"
 
@@ -177,22 +178,33 @@ type Cartridge struct {
 	Version            uint8
 	CartridgeType      Type
 	Japanese           bool
+	Hash               [sha256.Size]byte // SHA-256 of the ROM file
 }
 
 func NewCartridge(romPath string) (*Cartridge, error) {
-	c := &Cartridge{}
-
 	// Load the ROM file
 	romData, err := os.ReadFile(romPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ROM file: %w", err)
 	}
+	return ParseCartridge(romData)
+}
+
+// ParseCartridge loads a cartridge from the contents of a ROM file.
+func ParseCartridge(romData []byte) (*Cartridge, error) {
+	c := &Cartridge{}
+
 	if len(romData) < consts.ROMBankSize {
 		return nil, fmt.Errorf("ROM file is too small, must be at least %d bytes", consts.ROMBankSize)
 	}
 
+	c.Hash = sha256.Sum256(romData)
+
 	c.ROMSize = ROMSize(romData[0x148])
 	c.RAMSize = RAMSize(romData[0x149])
+	if c.ROMSize.NumberOfBanks() < 1 {
+		return nil, fmt.Errorf("unknown ROM size 0x%02X", romData[0x148])
+	}
 
 	// Copy the first 16KB to ROMBank0
 	copy(c.ROMBank0[:], romData[:consts.ROMBankSize])
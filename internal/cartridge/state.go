@@ -0,0 +1,41 @@
+package cartridge
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrStateRAMBanks = errors.New("save state has a different number of cartridge RAM banks")
+
+// SaveState writes the cartridge RAM to w.
+func (c *Cartridge) SaveState(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, uint8(len(c.CartridgeRAMBanks))); err != nil {
+		return fmt.Errorf("failed to write cartridge RAM bank count: %w", err)
+	}
+	for _, bank := range c.CartridgeRAMBanks {
+		if _, err := w.Write(bank); err != nil {
+			return fmt.Errorf("failed to write cartridge RAM: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadState restores cartridge RAM previously written with SaveState.
+func (c *Cartridge) LoadState(r io.Reader) error {
+	var banks uint8
+	if err := binary.Read(r, binary.LittleEndian, &banks); err != nil {
+		return fmt.Errorf("failed to read cartridge RAM bank count: %w", err)
+	}
+	if int(banks) != len(c.CartridgeRAMBanks) {
+		return fmt.Errorf("%w: got %d, want %d", ErrStateRAMBanks, banks, len(c.CartridgeRAMBanks))
+	}
+	// Banks are filled in place, as they are mapped into memory
+	for _, bank := range c.CartridgeRAMBanks {
+		if _, err := io.ReadFull(r, bank); err != nil {
+			return fmt.Errorf("failed to read cartridge RAM: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,75 @@
 package config
 
 type Config struct {
-	LogLevel   LogLevel `name:"log-level" description:"Logging level for the application. One of debug, info, warn, or error" default:"info"`
-	Scale      float64  `name:"scale" description:"Scale factor for the display." default:"4.0"`
-	Fullscreen bool     `name:"fullscreen" description:"Enable fullscreen mode."`
-	ROM        string   `name:"rom" description:"Path to the ROM file to load."`
-	BIOS       string   `name:"bios" description:"Path to the BIOS file to load."`
+	LogLevel       LogLevel `name:"log-level" description:"Logging level for the application. One of debug, info, warn, or error" default:"info"`
+	Scale          float64  `name:"scale" description:"Scale factor for the display." default:"4.0"`
+	Fullscreen     bool     `name:"fullscreen" description:"Enable fullscreen mode."`
+	ROM            string   `name:"rom" description:"Path to the ROM file to load."`
+	BIOS           string   `name:"bios" description:"Path to the BIOS file to load."`
+	Symbols        string   `name:"symbols" description:"Path to an RGBDS .sym file naming the ROM's addresses. Defaults to the ROM's path with a .sym extension, if it exists."`
+	RecordMovie    string   `name:"record-movie" description:"Path to record a movie of every frame's joypad input to."`
+	PlayMovie      string   `name:"play-movie" description:"Path of a movie to play back instead of live input."`
+	SerialOutput   string   `name:"serial-output" description:"Write bytes sent over the serial port to this file, or - for stdout."`
+	LinkListen     string   `name:"link-listen" description:"Wait for another go-gb to connect a link cable on this address, e.g. :5000."`
+	LinkConnect    string   `name:"link-connect" description:"Connect a link cable to another go-gb listening on this address, e.g. localhost:5000."`
+	PrinterOutput  string   `name:"printer-output" description:"Connect a Game Boy Printer and save printed images to this directory."`
+	StateDir       string   `name:"state-dir" description:"Directory to keep save state slots in. Defaults to the directory of the ROM."`
+	RewindSeconds  int      `name:"rewind-seconds" description:"Seconds of history kept for rewinding, or 0 to disable rewind." default:"10"`
+	RewindInterval int      `name:"rewind-interval" description:"Frames emulated between rewind snapshots." default:"1"`
+	Speed          float64  `name:"speed" description:"Emulation speed as a multiple of the Game Boy's." default:"1.0"`
+	SlowMotion     float64  `name:"slow-motion" description:"Emulation speed multiplier while slow motion is toggled on." default:"0.25"`
+	Uncapped       bool     `name:"uncapped" description:"Run as fast as possible instead of in real time."`
+	Trace          string   `name:"trace" description:"Write a Gameboy Doctor log line for every instruction run to this file, or - for stdout."`
+	TraceStart     string   `name:"trace-start" description:"Start tracing at the first instruction matching pc=addr, pc=start-end or cycle=count."`
+	TraceStop      string   `name:"trace-stop" description:"Stop tracing at the first instruction matching pc=addr, pc=start-end or cycle=count."`
+	TraceDoctor    bool     `name:"trace-doctor" description:"Make LY always read 0x90, as in the Gameboy Doctor reference logs, so the trace can be compared against them."`
+	Profile        string   `name:"profile" description:"Write a pprof profile of the M-cycles spent in each function of the ROM to this file on exit."`
+	Watch          []string `name:"watch" description:"Halt when the CPU accesses memory, written as access, address or range and optional condition, e.g. \"w C100\" or \"rw FF40-FF4B !=0\"."`
+	GDB            string   `name:"gdb" description:"Listen for a GDB remote protocol client on this address, e.g. :1234."`
+	Keys           Keys     `name:"keys"`
+	Gamepad        Gamepad  `name:"gamepad"`
+	Hotkeys        Hotkeys  `name:"hotkeys"`
+}
+
+// Keys maps the Game Boy buttons to keyboard keys, using ebiten key names.
+// An empty key leaves the button unbound.
+type Keys struct {
+	Up     string `name:"up" description:"Keyboard key for the d-pad up button." default:"ArrowUp"`
+	Down   string `name:"down" description:"Keyboard key for the d-pad down button." default:"ArrowDown"`
+	Left   string `name:"left" description:"Keyboard key for the d-pad left button." default:"ArrowLeft"`
+	Right  string `name:"right" description:"Keyboard key for the d-pad right button." default:"ArrowRight"`
+	A      string `name:"a" description:"Keyboard key for the A button." default:"X"`
+	B      string `name:"b" description:"Keyboard key for the B button." default:"Z"`
+	Start  string `name:"start" description:"Keyboard key for the Start button." default:"Enter"`
+	Select string `name:"select" description:"Keyboard key for the Select button." default:"Backspace"`
+}
+
+// Gamepad maps the Game Boy buttons to buttons of the standard gamepad layout.
+// An empty button leaves the Game Boy button unbound.
+type Gamepad struct {
+	Player int    `name:"player" description:"Which connected gamepad (0 for the first) controls this instance, or -1 to disable gamepad input." default:"0"`
+	Up     string `name:"up" description:"Standard gamepad button for the d-pad up button." default:"LeftTop"`
+	Down   string `name:"down" description:"Standard gamepad button for the d-pad down button." default:"LeftBottom"`
+	Left   string `name:"left" description:"Standard gamepad button for the d-pad left button." default:"LeftLeft"`
+	Right  string `name:"right" description:"Standard gamepad button for the d-pad right button." default:"LeftRight"`
+	A      string `name:"a" description:"Standard gamepad button for the A button." default:"RightRight"`
+	B      string `name:"b" description:"Standard gamepad button for the B button." default:"RightBottom"`
+	Start  string `name:"start" description:"Standard gamepad button for the Start button." default:"CenterRight"`
+	Select string `name:"select" description:"Standard gamepad button for the Select button." default:"CenterLeft"`
+}
+
+// Hotkeys maps emulator controls to keyboard keys, using ebiten key names.
+// An empty key leaves the control unbound.
+type Hotkeys struct {
+	FrameStep   string `name:"frame-step" description:"Keyboard key to step forward a single frame." default:"F7"`
+	Resume      string `name:"resume" description:"Keyboard key to resume execution." default:"F6"`
+	Halt        string `name:"halt" description:"Keyboard key to halt execution." default:"F5"`
+	Reset       string `name:"reset" description:"Keyboard key to reset the CPU." default:"F8"`
+	SaveState   string `name:"save-state" description:"Keyboard key to save a state to the current slot." default:"F1"`
+	LoadState   string `name:"load-state" description:"Keyboard key to load the state in the current slot." default:"F2"`
+	PrevSlot    string `name:"previous-slot" description:"Keyboard key to select the previous save state slot." default:"F3"`
+	NextSlot    string `name:"next-slot" description:"Keyboard key to select the next save state slot." default:"F4"`
+	Rewind      string `name:"rewind" description:"Keyboard key to hold to play the game backwards." default:"R"`
+	FastForward string `name:"fast-forward" description:"Keyboard key to hold to run as fast as possible." default:"Tab"`
+	SlowMotion  string `name:"slow-motion" description:"Keyboard key to toggle slow motion." default:"Backquote"`
 }
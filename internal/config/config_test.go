@@ -43,3 +43,51 @@ func TestLogLevelConstants(t *testing.T) {
 		})
 	}
 }
+
+func TestDuplicateBindings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		want   error
+	}{
+		{"defaults", func(_ *config.Config) {}, nil},
+		{"unbound keys", func(cfg *config.Config) {
+			cfg.Keys.A = ""
+			cfg.Keys.B = ""
+		}, nil},
+		{"duplicate button keys", func(cfg *config.Config) {
+			cfg.Keys.A = "Z"
+		}, config.ErrDuplicateKeyBinding},
+		{"hotkey shadows button", func(cfg *config.Config) {
+			cfg.Hotkeys.Reset = "x"
+		}, config.ErrDuplicateKeyBinding},
+		{"duplicate gamepad buttons", func(cfg *config.Config) {
+			cfg.Gamepad.Start = "CenterLeft"
+		}, config.ErrDuplicateGamepadBinding},
+		{"invalid gamepad player", func(cfg *config.Config) {
+			cfg.Gamepad.Player = -2
+		}, config.ErrInvalidGamepadPlayer},
+	}
+
+	defConfig, err := configulator.New[config.Config]().Default()
+	if err != nil {
+		t.Fatalf("failed to create default config: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defConfig
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() unexpected error = %v", err)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
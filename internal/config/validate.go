@@ -1,9 +1,24 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrInvalidLogLevel = errors.New("invalid log level provided")
+	ErrInvalidLogLevel         = errors.New("invalid log level provided")
+	ErrDuplicateKeyBinding     = errors.New("key is bound more than once")
+	ErrDuplicateGamepadBinding = errors.New("gamepad button is bound more than once")
+	ErrInvalidGamepadPlayer    = errors.New("invalid gamepad player provided")
+	ErrLinkListenAndConnect    = errors.New("link-listen and link-connect cannot be used together")
+	ErrSerialOutputAndLink     = errors.New("serial-output cannot be used with a link cable")
+	ErrInvalidSpeed            = errors.New("invalid speed provided")
+	ErrInvalidSlowMotion       = errors.New("invalid slow motion speed provided")
+	ErrInvalidRewindSeconds    = errors.New("invalid rewind seconds provided")
+	ErrInvalidRewindInterval   = errors.New("invalid rewind interval provided")
+	ErrPrinterAndSerialPeer    = errors.New("printer-output cannot be used with serial-output or a link cable")
+	ErrTraceConditionNoTrace   = errors.New("trace-start, trace-stop and trace-doctor require trace")
 )
 
 func (c Config) Validate() error {
@@ -14,5 +29,76 @@ func (c Config) Validate() error {
 		return ErrInvalidLogLevel
 	}
 
+	if key, ok := findDuplicate(
+		c.Keys.Up, c.Keys.Down, c.Keys.Left, c.Keys.Right,
+		c.Keys.A, c.Keys.B, c.Keys.Start, c.Keys.Select,
+		c.Hotkeys.FrameStep, c.Hotkeys.Resume, c.Hotkeys.Halt, c.Hotkeys.Reset,
+		c.Hotkeys.SaveState, c.Hotkeys.LoadState, c.Hotkeys.PrevSlot, c.Hotkeys.NextSlot,
+		c.Hotkeys.Rewind, c.Hotkeys.FastForward, c.Hotkeys.SlowMotion,
+	); ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateKeyBinding, key)
+	}
+
+	if c.Gamepad.Player < -1 {
+		return ErrInvalidGamepadPlayer
+	}
+
+	if button, ok := findDuplicate(
+		c.Gamepad.Up, c.Gamepad.Down, c.Gamepad.Left, c.Gamepad.Right,
+		c.Gamepad.A, c.Gamepad.B, c.Gamepad.Start, c.Gamepad.Select,
+	); ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateGamepadBinding, button)
+	}
+
+	if c.Speed <= 0 {
+		return ErrInvalidSpeed
+	}
+
+	if c.SlowMotion <= 0 {
+		return ErrInvalidSlowMotion
+	}
+
+	if c.RewindSeconds < 0 {
+		return ErrInvalidRewindSeconds
+	}
+
+	if c.RewindInterval < 1 {
+		return ErrInvalidRewindInterval
+	}
+
+	if c.LinkListen != "" && c.LinkConnect != "" {
+		return ErrLinkListenAndConnect
+	}
+
+	if c.SerialOutput != "" && (c.LinkListen != "" || c.LinkConnect != "") {
+		return ErrSerialOutputAndLink
+	}
+
+	if c.PrinterOutput != "" && (c.SerialOutput != "" || c.LinkListen != "" || c.LinkConnect != "") {
+		return ErrPrinterAndSerialPeer
+	}
+
+	if c.Trace == "" && (c.TraceStart != "" || c.TraceStop != "" || c.TraceDoctor) {
+		return ErrTraceConditionNoTrace
+	}
+
 	return nil
 }
+
+// findDuplicate returns the first binding that appears more than once,
+// ignoring case and unbound (empty) entries. Names that are aliases of the
+// same key are only caught once the window parses the bindings.
+func findDuplicate(bindings ...string) (string, bool) {
+	seen := make(map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		if binding == "" {
+			continue
+		}
+		normalized := strings.ToLower(binding)
+		if seen[normalized] {
+			return binding, true
+		}
+		seen[normalized] = true
+	}
+	return "", false
+}
@@ -12,3 +12,13 @@ const (
 	OAMSize              = 160   // 160 bytes of OAM (0xFE00 - 0xFE9F)
 	FrameBufferSize      = 23040 // 23040 bytes for a single frame buffer (160x144 pixels)
 )
+
+const (
+	ModelDMG = "DMG" // Original Game Boy
+)
+
+const (
+	CyclesPerSecond = 4194304 / 4                    // M-cycles per second
+	CyclesPerFrame  = 17556                          // M-cycles per frame, 154 lines of 456 dots
+	FrameRate       = 4194304.0 / 4 / CyclesPerFrame // About 59.73 frames per second
+)
@@ -0,0 +1,55 @@
+package cpu
+
+// maxCallDepth bounds the shadow call stack, so code that leaves calls
+// without returning, such as by jumping back to its main loop, can't grow it
+// forever. The outermost calls are forgotten first.
+const maxCallDepth = 256
+
+// Frame is a call on the shadow call stack.
+type Frame struct {
+	Site      uint16 // Address of the CALL or RST, or of the instruction an interrupt preempted
+	Target    uint16 // Address called, or the interrupt vector
+	Return    uint16 // Address pushed to return to
+	SP        uint16 // Address the return address was pushed to
+	Interrupt bool   // Set if an interrupt was dispatched rather than called
+}
+
+// CallStack returns the calls made by CALL, RST and interrupt dispatch that
+// haven't returned, the innermost last. Calls whose return address is above
+// SP are left out, as the stack has been unwound past them some other way.
+func (c *SM83) CallStack() []Frame {
+	return c.AppendCallStack(nil)
+}
+
+// AppendCallStack appends the call stack CallStack returns to dst, without
+// allocating if dst has room.
+func (c *SM83) AppendCallStack(dst []Frame) []Frame {
+	n := len(c.calls)
+	for n > 0 && c.calls[n-1].SP < c.rSP {
+		n--
+	}
+	return append(dst, c.calls[:n]...)
+}
+
+// pushCall records a call from site to target that returns to ret, once the
+// return address has been pushed.
+func (c *SM83) pushCall(site, target, ret uint16, interrupt bool) {
+	if len(c.calls) == maxCallDepth {
+		c.calls = append(c.calls[:0], c.calls[1:]...)
+	}
+	c.calls = append(c.calls, Frame{
+		Site:      site,
+		Target:    target,
+		Return:    ret,
+		SP:        c.rSP,
+		Interrupt: interrupt,
+	})
+}
+
+// popCalls forgets the calls returned from, once the return address has been
+// popped.
+func (c *SM83) popCalls() {
+	for len(c.calls) > 0 && c.calls[len(c.calls)-1].SP < c.rSP {
+		c.calls = c.calls[:len(c.calls)-1]
+	}
+}
@@ -0,0 +1,163 @@
+package cpu_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/interrupts"
+)
+
+func TestInterruptDispatch(t *testing.T) {
+	t.Parallel()
+
+	bus := &flatBus{}
+	bus.ram[0x0040] = 0x00 // NOP at the VBlank vector
+	irq := &interrupts.Controller{}
+	c := cpu.NewSM83(&config.Config{LogLevel: config.LogLevelError}, bus, irq)
+	c.SetRegisters(cpu.Registers{PC: 0x1234, SP: 0xD000, IME: true})
+
+	irq.Enable = byte(impls.VBlankInterrupt | impls.TimerInterrupt)
+	irq.SetInterruptFlag(impls.VBlankInterrupt, true)
+	irq.SetInterruptFlag(impls.JoypadInterrupt, true) // Not enabled
+	c.Step()
+
+	registers := c.Registers()
+	if registers.PC != 0x0041 {
+		t.Errorf("PC = 0x%04X, want 0x0041", registers.PC)
+	}
+	if registers.SP != 0xCFFE {
+		t.Errorf("SP = 0x%04X, want 0xCFFE", registers.SP)
+	}
+	if registers.IME {
+		t.Error("IME still set after dispatch")
+	}
+	// The high byte is pushed first
+	pushed := []busAccess{{addr: 0xCFFF, data: 0x12, write: true}, {addr: 0xCFFE, data: 0x34, write: true}}
+	if len(bus.accesses) < 2 || !slices.Equal(bus.accesses[:2], pushed) {
+		t.Errorf("bus accesses = %v, want %v first", bus.accesses, pushed)
+	}
+	if irq.GetInterruptFlag(impls.VBlankInterrupt) {
+		t.Error("VBlank still requested after dispatch")
+	}
+	if !irq.GetInterruptFlag(impls.JoypadInterrupt) {
+		t.Error("disabled joypad interrupt was cleared")
+	}
+}
+
+func TestCallStack(t *testing.T) {
+	t.Parallel()
+
+	bus := &flatBus{}
+	copy(bus.ram[0x0100:], []byte{0xCD, 0x00, 0x02}) // CALL 0x0200
+	copy(bus.ram[0x0200:], []byte{
+		0xCF, // RST 08H
+		0xC0, // RET NZ
+		0xC9, // RET
+	})
+	bus.ram[0x0008] = 0xC9 // RET
+	irq := &interrupts.Controller{}
+	c := cpu.NewSM83(&config.Config{LogLevel: config.LogLevelError}, bus, irq)
+	c.SetRegisters(cpu.Registers{F: byte(cpu.ZeroFlag), PC: 0x0100, SP: 0xD000})
+
+	call := cpu.Frame{Site: 0x0100, Target: 0x0200, Return: 0x0103, SP: 0xCFFE}
+	rst := cpu.Frame{Site: 0x0200, Target: 0x0008, Return: 0x0201, SP: 0xCFFC}
+	steps := []struct {
+		name string
+		want []cpu.Frame
+	}{
+		{name: "call", want: []cpu.Frame{call}},
+		{name: "rst", want: []cpu.Frame{call, rst}},
+		{name: "ret from rst", want: []cpu.Frame{call}},
+		{name: "ret not taken", want: []cpu.Frame{call}},
+		{name: "ret", want: []cpu.Frame{}},
+	}
+	for _, step := range steps {
+		c.Step()
+		if got := c.CallStack(); !slices.Equal(got, step.want) {
+			t.Errorf("after %s CallStack() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	// Resetting SP unwinds calls that never return
+	c.SetRegisters(cpu.Registers{PC: 0x0100, SP: 0xD000})
+	c.Step()
+	c.SetRegisters(cpu.Registers{PC: 0x0103, SP: 0xD000})
+	if got := c.CallStack(); len(got) != 0 {
+		t.Errorf("after resetting SP CallStack() = %+v, want none", got)
+	}
+}
+
+func TestConditionalCycles(t *testing.T) {
+	t.Parallel()
+
+	const (
+		jr   = 0x0112 // Where JR jumps to, 0x10 bytes past the instruction
+		jp   = 0x0210 // Where JP and CALL jump to
+		back = 0x0300 // Where RET returns to
+	)
+	tests := []struct {
+		name    string
+		op      byte
+		flag    cpu.Flag
+		set     bool   // Whether the branch is taken with the flag set
+		len     uint16 // The instruction's length
+		target  uint16
+		stack   int // How far SP moves when the branch is taken
+		taken   int
+		skipped int
+	}{
+		{name: "JR NZ", op: 0x20, flag: cpu.ZeroFlag, len: 2, target: jr, taken: 3, skipped: 2},
+		{name: "JR Z", op: 0x28, flag: cpu.ZeroFlag, set: true, len: 2, target: jr, taken: 3, skipped: 2},
+		{name: "JR NC", op: 0x30, flag: cpu.CarryFlag, len: 2, target: jr, taken: 3, skipped: 2},
+		{name: "JR C", op: 0x38, flag: cpu.CarryFlag, set: true, len: 2, target: jr, taken: 3, skipped: 2},
+		{name: "RET NZ", op: 0xC0, flag: cpu.ZeroFlag, len: 1, target: back, stack: 2, taken: 5, skipped: 2},
+		{name: "RET Z", op: 0xC8, flag: cpu.ZeroFlag, set: true, len: 1, target: back, stack: 2, taken: 5, skipped: 2},
+		{name: "RET NC", op: 0xD0, flag: cpu.CarryFlag, len: 1, target: back, stack: 2, taken: 5, skipped: 2},
+		{name: "RET C", op: 0xD8, flag: cpu.CarryFlag, set: true, len: 1, target: back, stack: 2, taken: 5, skipped: 2},
+		{name: "JP NZ", op: 0xC2, flag: cpu.ZeroFlag, len: 3, target: jp, taken: 4, skipped: 3},
+		{name: "JP Z", op: 0xCA, flag: cpu.ZeroFlag, set: true, len: 3, target: jp, taken: 4, skipped: 3},
+		{name: "JP NC", op: 0xD2, flag: cpu.CarryFlag, len: 3, target: jp, taken: 4, skipped: 3},
+		{name: "JP C", op: 0xDA, flag: cpu.CarryFlag, set: true, len: 3, target: jp, taken: 4, skipped: 3},
+		{name: "CALL NZ", op: 0xC4, flag: cpu.ZeroFlag, len: 3, target: jp, stack: -2, taken: 6, skipped: 3},
+		{name: "CALL Z", op: 0xCC, flag: cpu.ZeroFlag, set: true, len: 3, target: jp, stack: -2, taken: 6, skipped: 3},
+		{name: "CALL NC", op: 0xD4, flag: cpu.CarryFlag, len: 3, target: jp, stack: -2, taken: 6, skipped: 3},
+		{name: "CALL C", op: 0xDC, flag: cpu.CarryFlag, set: true, len: 3, target: jp, stack: -2, taken: 6, skipped: 3},
+	}
+	for _, tt := range tests {
+		for _, taken := range []bool{true, false} {
+			name, cycles, pc, sp := tt.name+" taken", tt.taken, tt.target, uint16(0xCFFE+tt.stack)
+			if !taken {
+				name, cycles, pc, sp = tt.name+" not taken", tt.skipped, 0x0100+tt.len, 0xCFFE
+			}
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				bus := &flatBus{}
+				copy(bus.ram[0x0100:], []byte{tt.op, 0x10, 0x02})
+				copy(bus.ram[0xCFFE:], []byte{0x00, 0x03}) // Return address
+				var f byte
+				if taken == tt.set {
+					f = byte(tt.flag)
+				}
+				c := cpu.NewSM83(&config.Config{LogLevel: config.LogLevelError}, bus, &interrupts.Controller{})
+				c.SetRegisters(cpu.Registers{F: f, PC: 0x0100, SP: 0xCFFE})
+
+				if got := c.Step(); got != cycles {
+					t.Errorf("Step() = %d M-cycles, want %d", got, cycles)
+				}
+				if bus.ticks != cycles {
+					t.Errorf("bus ticked %d times, want %d", bus.ticks, cycles)
+				}
+				if got := c.Registers().PC; got != pc {
+					t.Errorf("PC = 0x%04X, want 0x%04X", got, pc)
+				}
+				if got := c.Registers().SP; got != sp {
+					t.Errorf("SP = 0x%04X, want 0x%04X", got, sp)
+				}
+			})
+		}
+	}
+}
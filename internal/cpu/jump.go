@@ -18,6 +18,7 @@ func jrCond(cpu *SM83, condition bool) {
 	if condition {
 		cpu.rPC += uint16(int8(offset))
 	}
+	cpu.skipped = !condition
 }
 
 func ret(cpu *SM83) {
@@ -28,9 +29,11 @@ func ret(cpu *SM83) {
 	}
 	cpu.rSP += 2   // Increment stack pointer
 	cpu.rPC = addr // Set program counter to return address
+	cpu.popCalls()
 }
 
 func retCond(cpu *SM83, condition bool) {
+	cpu.skipped = !condition
 	if condition {
 		// Read the return address from the stack
 		addr, err := cpu.memory.Read16(cpu.rSP)
@@ -39,12 +42,19 @@ func retCond(cpu *SM83, condition bool) {
 		}
 		cpu.rSP += 2   // Increment stack pointer
 		cpu.rPC = addr // Set program counter to return address
-	} else {
-		cpu.rSP += 2 // Just skip the return address
+		cpu.popCalls()
 	}
 }
 
+// push pushes a return address the way the SM83 does, high byte first.
+func push(cpu *SM83, addr uint16) {
+	high, low := byte(addr>>8), byte(addr)
+	pushRegisterPair(cpu, &high, &low)
+}
+
 func call(cpu *SM83) {
+	site := cpu.rPC - 1
+
 	// Read the call address
 	addr, err := cpu.memory.Read16(cpu.rPC)
 	if err != nil {
@@ -53,44 +63,36 @@ func call(cpu *SM83) {
 	cpu.rPC += 2 // Increment program counter
 
 	// Push the current program counter onto the stack
-	err = cpu.memory.Write16(cpu.rSP-2, cpu.rPC)
-	if err != nil {
-		panic(err)
-	}
-	cpu.rSP -= 2 // Decrement stack pointer
+	push(cpu, cpu.rPC)
 
+	cpu.pushCall(site, addr, cpu.rPC, false)
 	cpu.rPC = addr // Set program counter to call address
 }
 
 func callCond(cpu *SM83, condition bool) {
-	if condition {
-		// Read the call address
-		addr, err := cpu.memory.Read16(cpu.rPC)
-		if err != nil {
-			panic(err)
-		}
-		cpu.rPC += 2 // Increment program counter
+	site := cpu.rPC - 1
+
+	// Read the call address, whether or not the call is taken
+	addr, err := cpu.memory.Read16(cpu.rPC)
+	if err != nil {
+		panic(err)
+	}
+	cpu.rPC += 2 // Increment program counter
 
+	cpu.skipped = !condition
+	if condition {
 		// Push the current program counter onto the stack
-		err = cpu.memory.Write16(cpu.rSP-2, cpu.rPC)
-		if err != nil {
-			panic(err)
-		}
-		cpu.rSP -= 2 // Decrement stack pointer
+		push(cpu, cpu.rPC)
 
+		cpu.pushCall(site, addr, cpu.rPC, false)
 		cpu.rPC = addr // Set program counter to call address
-	} else {
-		cpu.rPC += 2 // Just skip the call address
 	}
 }
 
 func rst(cpu *SM83, vector byte) {
 	// Push the current program counter onto the stack
-	err := cpu.memory.Write16(cpu.rSP-2, cpu.rPC)
-	if err != nil {
-		panic(err)
-	}
-	cpu.rSP -= 2 // Decrement stack pointer
+	push(cpu, cpu.rPC)
+	cpu.pushCall(cpu.rPC-1, uint16(vector), cpu.rPC, false)
 
 	// Set program counter to the reset vector
 	cpu.rPC = uint16(vector)
@@ -104,6 +106,7 @@ func reti(cpu *SM83) {
 	}
 	cpu.rSP += 2   // Increment stack pointer
 	cpu.rPC = addr // Set program counter to return address
+	cpu.popCalls()
 
 	// Enable interrupts
 	cpu.ime = true
@@ -121,17 +124,16 @@ func jp(cpu *SM83) {
 }
 
 func jpCond(cpu *SM83, condition bool) {
-	if condition {
-		// Read the jump address
-		addr, err := cpu.memory.Read16(cpu.rPC)
-		if err != nil {
-			panic(err)
-		}
-		cpu.rPC += 2 // Increment program counter
+	// Read the jump address, whether or not the jump is taken
+	addr, err := cpu.memory.Read16(cpu.rPC)
+	if err != nil {
+		panic(err)
+	}
+	cpu.rPC += 2 // Increment program counter
 
+	cpu.skipped = !condition
+	if condition {
 		cpu.rPC = addr // Set program counter to jump address
-	} else {
-		cpu.rPC += 2 // Just skip the jump address
 	}
 }
 
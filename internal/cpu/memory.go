@@ -0,0 +1,53 @@
+package cpu
+
+// Bus is what the CPU is connected to: the memory map and every device on
+// it. Reads and writes take no time by themselves; the CPU ticks the bus
+// once for each M-cycle it spends.
+type Bus interface {
+	Read(addr uint16) (byte, error)
+	Write(addr uint16, data byte) error
+	// Tick advances everything on the bus by an M-cycle.
+	Tick()
+}
+
+// clockedMemory is the bus as the CPU sees it. Every access takes an M-cycle,
+// so the bus is ticked before each one and other components observe the
+// access on the cycle it happens.
+type clockedMemory struct {
+	Bus
+	ticks int // M-cycles ticked since the last reset
+}
+
+func (m *clockedMemory) tick() {
+	m.ticks++
+	m.Bus.Tick()
+}
+
+func (m *clockedMemory) Read8(addr uint16) (uint8, error) {
+	m.tick()
+	return m.Bus.Read(addr)
+}
+
+func (m *clockedMemory) Write8(addr uint16, data uint8) error {
+	m.tick()
+	return m.Bus.Write(addr, data)
+}
+
+func (m *clockedMemory) Read16(addr uint16) (uint16, error) {
+	low, err := m.Read8(addr)
+	if err != nil {
+		return 0, err
+	}
+	high, err := m.Read8(addr + 1)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(high)<<8 | uint16(low), nil
+}
+
+func (m *clockedMemory) Write16(addr uint16, data uint16) error {
+	if err := m.Write8(addr, byte(data)); err != nil {
+		return err
+	}
+	return m.Write8(addr+1, byte(data>>8))
+}
@@ -5,3 +5,11 @@ func scf(cpu *SM83) {
 	cpu.SetFlag(HalfCarryFlag, false)
 	cpu.SetFlag(NegativeFlag, false)
 }
+
+// breakpoint runs LD B,B, which does nothing but is used by test ROMs such as
+// mooneye's as a software breakpoint.
+func breakpoint(c *SM83) {
+	if c.OnBreakpoint != nil {
+		c.OnBreakpoint()
+	}
+}
@@ -93,7 +93,7 @@ var opcodes = []*OpCode{
 	0x3D: {Name: "DEC A", Len: 1, Cycles: 1, Exec: func(c *SM83) { decRegister(c, &c.rA) }},
 	0x3E: {Name: "LD A,n", Len: 2, Cycles: 2, Exec: func(c *SM83) { ldRegisterImm(c, &c.rA) }},
 	// 0x3F: {Name: "CCF", Len: 1, Cycles: 1, Exec: func(c *SM83) { ccf(c) }},
-	0x40: {Name: "LD B,B", Len: 1, Cycles: 1, Exec: func(c *SM83) { ldRegisterRegister(c, &c.rB, &c.rB) }},
+	0x40: {Name: "LD B,B", Len: 1, Cycles: 1, Exec: func(c *SM83) { breakpoint(c) }},
 	0x41: {Name: "LD B,C", Len: 1, Cycles: 1, Exec: func(c *SM83) { ldRegisterRegister(c, &c.rB, &c.rC) }},
 	0x42: {Name: "LD B,D", Len: 1, Cycles: 1, Exec: func(c *SM83) { ldRegisterRegister(c, &c.rB, &c.rD) }},
 	0x43: {Name: "LD B,E", Len: 1, Cycles: 1, Exec: func(c *SM83) { ldRegisterRegister(c, &c.rB, &c.rE) }},
@@ -0,0 +1,254 @@
+package cpu_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/interrupts"
+)
+
+// The SingleStepTests SM83 vectors (https://github.com/SingleStepTests/sm83)
+// are too big to keep in the repository. Point SM83_TESTS at a checkout's
+// v1 directory to run all of them; otherwise only the few in testdata run.
+const singleStepEnv = "SM83_TESTS"
+
+// requiredVectors are the opcodes the whole suite must have vectors for. They
+// take different paths when their branch is taken and when it isn't, which
+// the vectors in testdata don't cover.
+//
+//nolint:gochecknoglobals
+var requiredVectors = []string{
+	"20", "28", "30", "38", // JR cc
+	"c0", "c8", "d0", "d8", // RET cc
+	"c2", "ca", "d2", "da", // JP cc
+	"c4", "cc", "d4", "dc", // CALL cc
+}
+
+type singleStepState struct {
+	PC  uint16      `json:"pc"`
+	SP  uint16      `json:"sp"`
+	A   byte        `json:"a"`
+	B   byte        `json:"b"`
+	C   byte        `json:"c"`
+	D   byte        `json:"d"`
+	E   byte        `json:"e"`
+	F   byte        `json:"f"`
+	H   byte        `json:"h"`
+	L   byte        `json:"l"`
+	IME byte        `json:"ime"`
+	RAM [][2]uint16 `json:"ram"`
+}
+
+func (s singleStepState) registers() cpu.Registers {
+	return cpu.Registers{
+		A: s.A, F: s.F, B: s.B, C: s.C, D: s.D, E: s.E, H: s.H, L: s.L,
+		SP: s.SP, PC: s.PC,
+		IME: s.IME != 0,
+	}
+}
+
+type singleStepTest struct {
+	Name    string            `json:"name"`
+	Initial singleStepState   `json:"initial"`
+	Final   singleStepState   `json:"final"`
+	Cycles  []json.RawMessage `json:"cycles"`
+}
+
+type busAccess struct {
+	addr  uint16
+	data  byte
+	write bool
+}
+
+func (a busAccess) String() string {
+	if a.write {
+		return fmt.Sprintf("write 0x%02X to 0x%04X", a.data, a.addr)
+	}
+	return fmt.Sprintf("read 0x%02X from 0x%04X", a.data, a.addr)
+}
+
+// flatBus is 64KB of RAM that records every access and counts the M-cycles
+// it's ticked for.
+type flatBus struct {
+	ram      [65536]byte
+	accesses []busAccess
+	ticks    int
+}
+
+func (b *flatBus) Read(addr uint16) (byte, error) {
+	b.accesses = append(b.accesses, busAccess{addr: addr, data: b.ram[addr]})
+	return b.ram[addr], nil
+}
+
+func (b *flatBus) Write(addr uint16, data byte) error {
+	b.accesses = append(b.accesses, busAccess{addr: addr, data: data, write: true})
+	b.ram[addr] = data
+	return nil
+}
+
+func (b *flatBus) Tick() {
+	b.ticks++
+}
+
+// expectedAccesses returns the reads and writes in a test's cycles, leaving
+// out internal cycles that don't touch the bus.
+func expectedAccesses(cycles []json.RawMessage) ([]busAccess, error) {
+	var accesses []busAccess
+	for _, raw := range cycles {
+		var cycle []any
+		if err := json.Unmarshal(raw, &cycle); err != nil {
+			return nil, fmt.Errorf("invalid cycle %s: %w", raw, err)
+		}
+		if len(cycle) != 3 {
+			continue
+		}
+		addr, _ := cycle[0].(float64)
+		data, _ := cycle[1].(float64)
+		kind, _ := cycle[2].(string)
+		switch {
+		case strings.HasPrefix(kind, "r"):
+			accesses = append(accesses, busAccess{addr: uint16(addr), data: byte(data)})
+		case strings.Contains(kind, "w"):
+			accesses = append(accesses, busAccess{addr: uint16(addr), data: byte(data), write: true})
+		}
+	}
+	return accesses, nil
+}
+
+// runSingleStep runs one test and returns why it failed, if it did. An
+// instruction the CPU doesn't implement is reported through unimplemented.
+func runSingleStep(test singleStepTest) (failure string, unimplemented bool) {
+	bus := &flatBus{}
+	for _, entry := range test.Initial.RAM {
+		bus.ram[entry[0]] = byte(entry[1])
+	}
+	c := cpu.NewSM83(&config.Config{LogLevel: config.LogLevelError}, bus, &interrupts.Controller{})
+	c.SetRegisters(test.Initial.registers())
+
+	var cycles int
+	panicked := func() (message any) {
+		defer func() {
+			message = recover()
+		}()
+		cycles = c.Step()
+		return nil
+	}()
+	if panicked != nil {
+		if strings.Contains(fmt.Sprint(panicked), "Unknown") {
+			return "", true
+		}
+		return fmt.Sprintf("panicked: %v", panicked), false
+	}
+
+	var failures []string
+	if got, want := c.Registers(), test.Final.registers(); got != want {
+		failures = append(failures, fmt.Sprintf("registers = %+v, want %+v", got, want))
+	}
+	for _, entry := range test.Final.RAM {
+		if got := bus.ram[entry[0]]; got != byte(entry[1]) {
+			failures = append(failures, fmt.Sprintf("0x%04X = 0x%02X, want 0x%02X", entry[0], got, entry[1]))
+		}
+	}
+	if cycles != len(test.Cycles) {
+		failures = append(failures, fmt.Sprintf("took %d M-cycles, want %d", cycles, len(test.Cycles)))
+	}
+	want, err := expectedAccesses(test.Cycles)
+	if err != nil {
+		return err.Error(), false
+	}
+	for i := range max(len(bus.accesses), len(want)) {
+		switch {
+		case i >= len(want):
+			failures = append(failures, fmt.Sprintf("extra bus access %d: %s", i, bus.accesses[i]))
+		case i >= len(bus.accesses):
+			failures = append(failures, fmt.Sprintf("missing bus access %d: %s", i, want[i]))
+		case bus.accesses[i] != want[i]:
+			failures = append(failures, fmt.Sprintf("bus access %d: %s, want %s", i, bus.accesses[i], want[i]))
+		}
+	}
+	return strings.Join(failures, "\n"), false
+}
+
+func TestSingleStep(t *testing.T) {
+	t.Parallel()
+
+	dir := os.Getenv(singleStepEnv)
+	full := dir != ""
+	if !full {
+		dir = filepath.Join("testdata", "sm83")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("failed to find test vectors: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no test vectors in %s", dir)
+	}
+	required := make(map[string]bool)
+	if full {
+		found := make(map[string]bool)
+		for _, file := range files {
+			found[strings.ToLower(strings.TrimSuffix(filepath.Base(file), ".json"))] = true
+		}
+		for _, opcode := range requiredVectors {
+			if !found[opcode] {
+				t.Errorf("no test vectors for opcode 0x%s in %s", strings.ToUpper(opcode), dir)
+			}
+			required[opcode] = true
+		}
+	}
+
+	// Unimplemented opcodes are skipped, so count them once every opcode has
+	// run rather than let the skips hide how much of the suite didn't run
+	var (
+		mu      sync.Mutex
+		missing []string
+	)
+	t.Cleanup(func() {
+		if len(missing) == 0 {
+			return
+		}
+		slices.Sort(missing)
+		t.Logf("%d of %d opcodes are not implemented: %s", len(missing), len(files), strings.Join(missing, " "))
+	})
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read test vectors: %v", err)
+			}
+			var tests []singleStepTest
+			if err := json.Unmarshal(data, &tests); err != nil {
+				t.Fatalf("failed to parse test vectors: %v", err)
+			}
+			for _, test := range tests {
+				failure, unimplemented := runSingleStep(test)
+				if unimplemented {
+					if required[strings.ToLower(strings.Fields(test.Name)[0])] {
+						t.Fatalf("%s: required instruction is not implemented", test.Name)
+					}
+					mu.Lock()
+					missing = append(missing, name)
+					mu.Unlock()
+					t.Skipf("%s: instruction is not implemented", test.Name)
+				}
+				if failure != "" {
+					// One failure is enough to go on, and the rest are usually the same
+					t.Fatalf("%s:\n%s", test.Name, failure)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+)
+
+type handler func(s *Session, args json.RawMessage) (any, error)
+
+// handlers handles each command the session supports.
+//
+//nolint:gochecknoglobals
+var handlers = map[string]handler{
+	"initialize":              initialize,
+	"launch":                  launch,
+	"setBreakpoints":          setBreakpoints,
+	"setFunctionBreakpoints":  setFunctionBreakpoints,
+	"setExceptionBreakpoints": func(*Session, json.RawMessage) (any, error) { return nil, nil },
+	"configurationDone":       configurationDone,
+	"threads":                 threads,
+	"stackTrace":              stackTrace,
+	"scopes":                  scopes,
+	"variables":               variables,
+	"continue":                cont,
+	"next":                    next,
+	"stepIn":                  stepIn,
+	"stepOut":                 stepOut,
+	"pause":                   pause,
+	"terminate":               terminate,
+	"disconnect":              disconnect,
+}
+
+// placed is a breakpoint and, if it is verified, where it was placed.
+type placed struct {
+	breakpoint
+	loc disasm.Location
+}
+
+func (s *Session) handle(req *request) {
+	h, ok := handlers[req.Command]
+	if !ok {
+		s.respond(req, nil, fmt.Errorf("%w: %s", ErrUnknownCommand, req.Command))
+		return
+	}
+	body, err := h(s, req.Arguments)
+	s.respond(req, body, err)
+	// Events that must follow the response
+	for _, f := range s.later {
+		f()
+	}
+	s.later = nil
+}
+
+// decode decodes a request's arguments, which may be missing.
+func decode[T any](args json.RawMessage) (T, error) {
+	var v T
+	if len(args) == 0 {
+		return v, nil
+	}
+	if err := json.Unmarshal(args, &v); err != nil {
+		return v, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return v, nil
+}
+
+func initialize(_ *Session, _ json.RawMessage) (any, error) {
+	return capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsFunctionBreakpoints:      true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+func launch(s *Session, raw json.RawMessage) (any, error) {
+	args, err := decode[launchArguments](raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.launch(args); err != nil {
+		return nil, err
+	}
+	s.later = append(s.later, func() { s.event("initialized", nil) })
+	return nil, nil
+}
+
+func setBreakpoints(s *Session, raw json.RawMessage) (any, error) {
+	args, err := decode[setBreakpointsArguments](raw)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source path: %w", err)
+	}
+	var list []placed
+	for _, requested := range args.Breakpoints {
+		bp := placed{breakpoint: breakpoint{ID: s.nextID, Line: requested.Line, Source: &args.Source}}
+		s.nextID++
+		loc, line, ok := s.lines.location(path, requested.Line)
+		switch {
+		case s.machine.Symbols() == nil:
+			bp.Message = "No symbols are loaded to find the line's address from"
+		case !ok:
+			bp.Message = "No instruction could be matched to the line"
+		default:
+			bp.Verified, bp.Line, bp.loc = true, line, loc
+		}
+		list = append(list, bp)
+	}
+	s.breakpoints[path] = list
+	s.placeBreakpoints()
+	return breakpointsBody{Breakpoints: unplaced(list)}, nil
+}
+
+func setFunctionBreakpoints(s *Session, raw json.RawMessage) (any, error) {
+	args, err := decode[setFunctionBreakpointsArguments](raw)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	s.functions = nil
+	for _, requested := range args.Breakpoints {
+		bp := placed{breakpoint: breakpoint{ID: s.nextID}}
+		s.nextID++
+		if sym, ok := s.machine.Symbols().Find(requested.Name); ok && sym.Addr < 0x8000 {
+			bp.Verified, bp.loc = true, disasm.Location{Bank: sym.Bank, Addr: sym.Addr}
+			if pos, ok := s.lines.position(bp.loc); ok {
+				bp.Source = &source{Name: filepath.Base(pos.path), Path: pos.path}
+				bp.Line = pos.line
+			}
+		} else {
+			bp.Message = fmt.Sprintf("No label %s in ROM", requested.Name)
+		}
+		s.functions = append(s.functions, bp)
+	}
+	s.placeBreakpoints()
+	return breakpointsBody{Breakpoints: unplaced(s.functions)}, nil
+}
+
+// placeBreakpoints indexes the verified breakpoints by location.
+func (s *Session) placeBreakpoints() {
+	s.locations = make(map[disasm.Location][]int)
+	place := func(list []placed) {
+		for _, bp := range list {
+			if bp.Verified {
+				s.locations[bp.loc] = append(s.locations[bp.loc], bp.ID)
+			}
+		}
+	}
+	for _, list := range s.breakpoints {
+		place(list)
+	}
+	place(s.functions)
+}
+
+func unplaced(list []placed) []breakpoint {
+	out := make([]breakpoint, len(list))
+	for i, bp := range list {
+		out[i] = bp.breakpoint
+	}
+	return out
+}
+
+func configurationDone(s *Session, _ json.RawMessage) (any, error) {
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	if s.stopOnEntry {
+		s.later = append(s.later, func() { s.stopped("entry", "", nil) })
+	} else {
+		s.later = append(s.later, func() { s.run("", func() bool { return false }) })
+	}
+	return nil, nil
+}
+
+func threads(_ *Session, _ json.RawMessage) (any, error) {
+	return threadsBody{Threads: []thread{{ID: threadID, Name: "SM83"}}}, nil
+}
+
+func stackTrace(s *Session, raw json.RawMessage) (any, error) {
+	args, err := decode[stackTraceArguments](raw)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	frames := s.frames()
+	total := len(frames)
+	frames = frames[min(args.StartFrame, total):]
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+	return stackTraceBody{StackFrames: frames, TotalFrames: total}, nil
+}
+
+// frames returns the PC followed by the sites of the calls that haven't
+// returned, innermost first.
+func (s *Session) frames() []stackFrame {
+	calls := s.machine.CPU.CallStack()
+	frames := []stackFrame{s.frame(0, s.machine.CPU.GetPC())}
+	for i := len(calls) - 1; i >= 0; i-- {
+		frames = append(frames, s.frame(len(frames), calls[i].Site))
+	}
+	return frames
+}
+
+// frame describes addr as a frame named after the label before it.
+func (s *Session) frame(id int, addr uint16) stackFrame {
+	loc := s.location(addr)
+	f := stackFrame{
+		ID:                          id,
+		Name:                        fmt.Sprintf("0x%04X", addr),
+		InstructionPointerReference: fmt.Sprintf("0x%04X", addr),
+	}
+	if sym, ok := s.machine.Symbols().Nearest(loc.Bank, addr); ok {
+		f.Name = sym.Name
+	}
+	if pos, ok := s.lines.position(loc); ok {
+		f.Source = &source{Name: filepath.Base(pos.path), Path: pos.path}
+		f.Line, f.Column = pos.line, 1
+	}
+	return f
+}
+
+func cont(s *Session, _ json.RawMessage) (any, error) {
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	s.later = append(s.later, func() { s.run("", func() bool { return false }) })
+	return continueBody{AllThreadsContinued: true}, nil
+}
+
+// next runs an instruction, running whole subroutines called by CALL and
+// RST.
+func next(s *Session, _ json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	r := s.machine.CPU.Registers()
+	inst := disasm.Decode(s.machine.Peek, r.PC)
+	after := r.PC + uint16(inst.Len())
+	done := func() bool {
+		// Recursive calls pass the same PC deeper in the stack
+		current := s.machine.CPU.Registers()
+		return current.PC == after && current.SP >= r.SP
+	}
+	if inst.Flow != disasm.FlowCall {
+		done = func() bool { return true }
+	}
+	s.later = append(s.later, func() { s.run("step", done) })
+	return nil, nil
+}
+
+func stepIn(s *Session, _ json.RawMessage) (any, error) {
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	s.later = append(s.later, func() { s.run("step", func() bool { return true }) })
+	return nil, nil
+}
+
+// stepOut runs until the innermost call returns, or until a breakpoint
+// outside of any call.
+func stepOut(s *Session, _ json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	depth := len(s.machine.CPU.CallStack())
+	s.later = append(s.later, func() {
+		s.run("step", func() bool { return depth > 0 && len(s.machine.CPU.CallStack()) < depth })
+	})
+	return nil, nil
+}
+
+func pause(s *Session, _ json.RawMessage) (any, error) {
+	s.pause.Store(true)
+	return nil, nil
+}
+
+func terminate(s *Session, _ json.RawMessage) (any, error) {
+	s.stop()
+	s.later = append(s.later, func() { s.event("terminated", nil) })
+	return nil, nil
+}
+
+func disconnect(s *Session, _ json.RawMessage) (any, error) {
+	s.done.Store(true)
+	s.stop()
+	return nil, nil
+}
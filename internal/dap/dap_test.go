@@ -0,0 +1,322 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/dap"
+)
+
+const source = `SECTION "Entry", ROM0[$100]
+Entry:
+    nop
+    jp Main
+
+SECTION "Main", ROM0[$150]
+Main:
+    ld a, $91
+    ldh [$ff40], a ; Turn the LCD on
+    ld hl, $c000
+.loop
+    inc [hl]
+    call Increment
+    jr .loop
+
+Increment:
+    inc [hl]
+    ret
+`
+
+const symbolFile = `; File generated by rgblink
+00:0100 Entry
+00:0150 Main
+00:0157 Main.loop
+00:015d Increment
+`
+
+// writeProject writes a ROM assembled from source, with its symbols.
+func writeProject(t *testing.T) (rom, asm string) {
+	t.Helper()
+
+	data := make([]byte, 2*16384)
+	copy(data[0x100:], []byte{0x00, 0xC3, 0x50, 0x01})
+	copy(data[0x150:], []byte{
+		0x3E, 0x91, // ld a, $91
+		0xE0, 0x40, // ldh [$ff40], a
+		0x21, 0x00, 0xC0, // ld hl, $c000
+		0x34,             // inc [hl]
+		0xCD, 0x5D, 0x01, // call Increment
+		0x18, 0xFA, // jr .loop
+		0x34, // inc [hl]
+		0xC9, // ret
+	})
+	dir := t.TempDir()
+	rom = filepath.Join(dir, "game.gb")
+	asm = filepath.Join(dir, "game.asm")
+	for path, content := range map[string][]byte{
+		rom:                             data,
+		asm:                             []byte(source),
+		filepath.Join(dir, "game.sym"):  []byte(symbolFile),
+		filepath.Join(dir, "notes.txt"): []byte("Not source"),
+	} {
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	return rom, asm
+}
+
+type message struct {
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a session over pipes, reading its messages in the
+// background as an editor would.
+type client struct {
+	t        *testing.T
+	in       io.Writer
+	messages chan message
+	seq      int
+	events   []message
+}
+
+func newClient(t *testing.T, in io.Writer, out io.Reader) *client {
+	t.Helper()
+
+	c := &client{t: t, in: in, messages: make(chan message, 64)}
+	go func() {
+		defer close(c.messages)
+		r := bufio.NewReader(out)
+		for {
+			m, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			c.messages <- m
+		}
+	}()
+	return c
+}
+
+func readMessage(r *bufio.Reader) (message, error) {
+	length := 0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return message{}, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+			length, _ = strconv.Atoi(value)
+		}
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return message{}, err
+	}
+	var m message
+	err := json.Unmarshal(content, &m)
+	return m, err
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+
+	select {
+	case m, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("session closed its output")
+		}
+		return m
+	case <-time.After(10 * time.Second):
+		c.t.Fatal("timed out waiting for the session")
+	}
+	return message{}
+}
+
+// request sends a request and returns the body of its response, collecting
+// the events before it.
+func (c *client) request(command string, args any) json.RawMessage {
+	c.t.Helper()
+
+	c.seq++
+	content, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		c.t.Fatalf("failed to encode request: %v", err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		c.t.Fatalf("failed to send %s: %v", command, err)
+	}
+	for {
+		m := c.read()
+		if m.Type == "event" {
+			c.events = append(c.events, m)
+			continue
+		}
+		if m.RequestSeq != c.seq || !m.Success {
+			c.t.Fatalf("%s failed: %+v", command, m)
+		}
+		return m.Body
+	}
+}
+
+// stopped waits for a stopped event and returns its reason.
+func (c *client) stopped() string {
+	c.t.Helper()
+
+	for {
+		var m message
+		if len(c.events) > 0 {
+			m, c.events = c.events[0], c.events[1:]
+		} else {
+			m = c.read()
+		}
+		if m.Event == "stopped" {
+			var body struct {
+				Reason string `json:"reason"`
+			}
+			_ = json.Unmarshal(m.Body, &body)
+			return body.Reason
+		}
+	}
+}
+
+// stack returns the names and lines of the frames.
+func (c *client) stack() []string {
+	c.t.Helper()
+
+	var body struct {
+		StackFrames []struct {
+			Name string `json:"name"`
+			Line int    `json:"line"`
+		} `json:"stackFrames"`
+	}
+	if err := json.Unmarshal(c.request("stackTrace", map[string]any{"threadId": 1}), &body); err != nil {
+		c.t.Fatalf("failed to parse stack trace: %v", err)
+	}
+	var frames []string
+	for _, f := range body.StackFrames {
+		frames = append(frames, fmt.Sprintf("%s:%d", f.Name, f.Line))
+	}
+	return frames
+}
+
+func (c *client) variable(reference int, name string) string {
+	c.t.Helper()
+
+	var body struct {
+		Variables []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+	if err := json.Unmarshal(c.request("variables", map[string]any{"variablesReference": reference}), &body); err != nil {
+		c.t.Fatalf("failed to parse variables: %v", err)
+	}
+	for _, v := range body.Variables {
+		if v.Name == name {
+			return v.Value
+		}
+	}
+	c.t.Fatalf("no variable %s", name)
+	return ""
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	rom, asm := writeProject(t)
+	requests, in := io.Pipe()
+	out, responses := io.Pipe()
+	session := dap.New(&config.Config{LogLevel: config.LogLevelError}, requests, responses)
+	served := make(chan error, 1)
+	go func() {
+		served <- session.Serve()
+		responses.Close()
+	}()
+	c := newClient(t, in, out)
+
+	c.request("initialize", map[string]any{"adapterID": "go-gb"})
+	c.request("launch", map[string]any{"program": rom, "stopOnEntry": true})
+
+	var set struct {
+		Breakpoints []struct {
+			Verified bool `json:"verified"`
+			Line     int  `json:"line"`
+		} `json:"breakpoints"`
+	}
+	body := c.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": asm},
+		"breakpoints": []map[string]any{{"line": 16}, {"line": 40}},
+	})
+	if err := json.Unmarshal(body, &set); err != nil {
+		t.Fatalf("failed to parse breakpoints: %v", err)
+	}
+	if len(set.Breakpoints) != 2 || !set.Breakpoints[0].Verified || set.Breakpoints[0].Line != 17 || set.Breakpoints[1].Verified {
+		t.Errorf("setBreakpoints = %+v, want line 17 verified and line 40 not", set.Breakpoints)
+	}
+
+	c.request("configurationDone", nil)
+	steps := []struct {
+		command string
+		reason  string
+		stack   []string
+	}{
+		{command: "", reason: "entry", stack: []string{"Entry:3"}},
+		{command: "continue", reason: "breakpoint", stack: []string{"Increment:17", "Main.loop:13"}},
+		{command: "stepIn", reason: "step", stack: []string{"Increment:18", "Main.loop:13"}},
+		{command: "stepOut", reason: "step", stack: []string{"Main.loop:14"}},
+		{command: "next", reason: "step", stack: []string{"Main.loop:12"}},
+		{command: "next", reason: "step", stack: []string{"Main.loop:13"}},
+		{command: "next", reason: "breakpoint", stack: []string{"Increment:17", "Main.loop:13"}},
+	}
+	for _, step := range steps {
+		if step.command != "" {
+			c.request(step.command, map[string]any{"threadId": 1})
+		}
+		if got := c.stopped(); got != step.reason {
+			t.Errorf("%s stopped for %q, want %q", step.command, got, step.reason)
+		}
+		if got := c.stack(); strings.Join(got, " ") != strings.Join(step.stack, " ") {
+			t.Errorf("after %s stack = %v, want %v", step.command, got, step.stack)
+		}
+	}
+
+	c.request("scopes", map[string]any{"frameId": 0})
+	if got := c.variable(1, "HL"); got != "0xC000" {
+		t.Errorf("HL = %s, want 0xC000", got)
+	}
+	if got := c.variable(2, "LCDC"); got != "0x91" {
+		t.Errorf("LCDC = %s, want 0x91", got)
+	}
+
+	c.request("setBreakpoints", map[string]any{"source": map[string]any{"path": asm}, "breakpoints": []any{}})
+	c.request("continue", map[string]any{"threadId": 1})
+	time.Sleep(10 * time.Millisecond)
+	c.request("pause", map[string]any{"threadId": 1})
+	if got := c.stopped(); got != "pause" {
+		t.Errorf("pause stopped for %q", got)
+	}
+
+	c.request("disconnect", nil)
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+//nolint:gochecknoglobals
+var (
+	// labelPattern matches a label at the start of a line: a global label,
+	// perhaps with a local part, followed by one or two colons, or a local
+	// label with an optional colon.
+	labelPattern = regexp.MustCompile(`^(?:([A-Za-z_][\w#@]*(?:\.[\w#@]+)?)::?|(\.[\w#@]+):?)`)
+
+	sourceExtensions = map[string]bool{".asm": true, ".s": true, ".inc": true, ".z80": true, ".sm83": true}
+
+	mnemonics = map[string]bool{
+		"adc": true, "add": true, "and": true, "bit": true, "call": true, "ccf": true, "cp": true,
+		"cpl": true, "daa": true, "dec": true, "di": true, "ei": true, "halt": true, "inc": true,
+		"jp": true, "jr": true, "ld": true, "ldd": true, "ldh": true, "ldi": true, "nop": true,
+		"or": true, "pop": true, "push": true, "res": true, "ret": true, "reti": true, "rl": true,
+		"rla": true, "rlc": true, "rlca": true, "rr": true, "rra": true, "rrc": true, "rrca": true,
+		"rst": true, "sbc": true, "scf": true, "set": true, "sla": true, "sra": true, "srl": true,
+		"stop": true, "sub": true, "swap": true, "xor": true,
+	}
+
+	// quietDirectives are directives that don't emit bytes, so the lines
+	// after them follow on from the lines before.
+	quietDirectives = map[string]bool{
+		"def": true, "redef": true, "export": true, "global": true, "purge": true, "assert": true,
+		"static_assert": true, "opt": true, "pusho": true, "popo": true, "print": true,
+		"println": true, "warn": true, "charmap": true, "rsreset": true, "rsset": true,
+	}
+
+	// quietOperators define constants when they follow a name.
+	quietOperators = map[string]bool{"equ": true, "equs": true, "=": true, "set": true, "rb": true, "rw": true, "rl": true}
+)
+
+type position struct {
+	path string
+	line int
+}
+
+// lineTable maps between lines of RGBDS source and the instructions
+// assembled from them. RGBDS doesn't write line numbers out, so every label
+// in the symbols anchors its line to an address, and the instructions on the
+// lines after it are matched to the ROM one by one. Matching stops at
+// anything that emits bytes it can't follow, such as data or a macro, until
+// the next label.
+type lineTable struct {
+	rom       []byte
+	symbols   *symbols.Table
+	lines     map[string]map[int]disasm.Location // The instruction on each line of each file
+	positions map[disasm.Location]position
+}
+
+func newLineTable(rom []byte, table *symbols.Table) *lineTable {
+	return &lineTable{
+		rom:       rom,
+		symbols:   table,
+		lines:     make(map[string]map[int]disasm.Location),
+		positions: make(map[disasm.Location]position),
+	}
+}
+
+// scanDir scans the source files under dir.
+func (t *lineTable) scanDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !sourceExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		return t.scan(path)
+	})
+}
+
+// scan matches the lines of a source file to the ROM, unless it has been
+// already.
+func (t *lineTable) scan(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to find source: %w", err)
+	}
+	if _, ok := t.lines[path]; ok {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer file.Close()
+
+	lines := make(map[int]disasm.Location)
+	t.lines[path] = lines
+	var (
+		loc   disasm.Location
+		valid bool   // Set while loc is known
+		scope string // The last global label, which local labels belong to
+	)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		text, _, _ := strings.Cut(scanner.Text(), ";")
+		if match := labelPattern.FindStringSubmatch(text); match != nil {
+			name := match[1]
+			if name == "" {
+				name = scope + match[2]
+			} else if !strings.Contains(name, ".") {
+				scope = name
+			}
+			if sym, ok := t.symbols.Find(name); ok && sym.Addr < 0x8000 {
+				loc, valid = disasm.Location{Bank: sym.Bank, Addr: sym.Addr}, true
+			}
+			text = text[len(match[0]):]
+		}
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " , "))
+		if len(fields) == 0 {
+			continue
+		}
+		word := strings.ToLower(fields[0])
+		switch {
+		case quietDirectives[word], len(fields) > 1 && quietOperators[strings.ToLower(fields[1])]:
+		case mnemonics[word] && valid:
+			inst := disasm.Decode(t.reader(loc.Bank), loc.Addr)
+			if family(inst.Mnemonic) != family(word) {
+				valid = false
+				continue
+			}
+			lines[n] = loc
+			if _, ok := t.positions[loc]; !ok {
+				t.positions[loc] = position{path: path, line: n}
+			}
+			loc.Addr += uint16(inst.Len())
+		default:
+			valid = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read source: %w", err)
+	}
+	return nil
+}
+
+// family groups the ways RGBDS accepts of writing the same loads.
+func family(mnemonic string) string {
+	switch mnemonic {
+	case "ldh", "ldi", "ldd":
+		return "ld"
+	}
+	return mnemonic
+}
+
+// reader reads the ROM as mapped with bank at 0x4000-0x7FFF.
+func (t *lineTable) reader(bank int) func(addr uint16) byte {
+	return func(addr uint16) byte {
+		offset := int(addr)
+		if addr >= 0x4000 {
+			offset = bank*consts.ROMBankSize + int(addr) - 0x4000
+		}
+		if addr >= 0x8000 || offset >= len(t.rom) {
+			return 0xFF
+		}
+		return t.rom[offset]
+	}
+}
+
+// location returns the instruction on the first line at or after line that
+// has one, and that line.
+func (t *lineTable) location(path string, line int) (disasm.Location, int, bool) {
+	if err := t.scan(path); err != nil {
+		return disasm.Location{}, 0, false
+	}
+	path, _ = filepath.Abs(path)
+	lines := t.lines[path]
+	numbers := make([]int, 0, len(lines))
+	for n := range lines {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	i := sort.SearchInts(numbers, line)
+	if i == len(numbers) {
+		return disasm.Location{}, 0, false
+	}
+	return lines[numbers[i]], numbers[i], true
+}
+
+// position returns the line the instruction at loc was assembled from.
+func (t *lineTable) position(loc disasm.Location) (position, bool) {
+	pos, ok := t.positions[loc]
+	return pos, ok
+}
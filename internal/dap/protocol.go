@@ -0,0 +1,196 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errNoContentLength = errors.New("message has no Content-Length header")
+
+// request is a request from the client. Its arguments are decoded by the
+// handler for its command.
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// readMessage reads the JSON content of a message, after its headers.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q: %w", value, err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errNoContentLength
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// writeMessage writes a message with its Content-Length header.
+func writeMessage(w io.Writer, message any) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(content), content); err != nil {
+		return err
+	}
+	return nil
+}
+
+// The bodies and arguments used from the protocol, named as in its
+// specification.
+type (
+	capabilities struct {
+		SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+		SupportsFunctionBreakpoints      bool `json:"supportsFunctionBreakpoints"`
+		SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+	}
+
+	launchArguments struct {
+		Program     string `json:"program"`     // Path to the ROM
+		Symbols     string `json:"symbols"`     // Path to the .sym file, by default next to the ROM
+		SourceRoot  string `json:"sourceRoot"`  // Directory of the ROM's source, by default the ROM's
+		StopOnEntry bool   `json:"stopOnEntry"` // Stop before running the first instruction
+	}
+
+	source struct {
+		Name string `json:"name,omitempty"`
+		Path string `json:"path,omitempty"`
+	}
+
+	sourceBreakpoint struct {
+		Line int `json:"line"`
+	}
+
+	setBreakpointsArguments struct {
+		Source      source             `json:"source"`
+		Breakpoints []sourceBreakpoint `json:"breakpoints"`
+	}
+
+	functionBreakpoint struct {
+		Name string `json:"name"`
+	}
+
+	setFunctionBreakpointsArguments struct {
+		Breakpoints []functionBreakpoint `json:"breakpoints"`
+	}
+
+	breakpoint struct {
+		ID       int     `json:"id"`
+		Verified bool    `json:"verified"`
+		Message  string  `json:"message,omitempty"`
+		Source   *source `json:"source,omitempty"`
+		Line     int     `json:"line,omitempty"`
+	}
+
+	breakpointsBody struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}
+
+	thread struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	threadsBody struct {
+		Threads []thread `json:"threads"`
+	}
+
+	stackTraceArguments struct {
+		StartFrame int `json:"startFrame"`
+		Levels     int `json:"levels"`
+	}
+
+	stackFrame struct {
+		ID                          int     `json:"id"`
+		Name                        string  `json:"name"`
+		Source                      *source `json:"source,omitempty"`
+		Line                        int     `json:"line"`
+		Column                      int     `json:"column"`
+		InstructionPointerReference string  `json:"instructionPointerReference"`
+	}
+
+	stackTraceBody struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+		TotalFrames int          `json:"totalFrames"`
+	}
+
+	scope struct {
+		Name               string `json:"name"`
+		VariablesReference int    `json:"variablesReference"`
+		Expensive          bool   `json:"expensive"`
+	}
+
+	scopesBody struct {
+		Scopes []scope `json:"scopes"`
+	}
+
+	variablesArguments struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+
+	variable struct {
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+		VariablesReference int    `json:"variablesReference"`
+	}
+
+	variablesBody struct {
+		Variables []variable `json:"variables"`
+	}
+
+	continueBody struct {
+		AllThreadsContinued bool `json:"allThreadsContinued"`
+	}
+
+	stoppedBody struct {
+		Reason            string `json:"reason"`
+		Description       string `json:"description,omitempty"`
+		ThreadID          int    `json:"threadId"`
+		AllThreadsStopped bool   `json:"allThreadsStopped"`
+		HitBreakpointIDs  []int  `json:"hitBreakpointIds,omitempty"`
+	}
+)
@@ -0,0 +1,257 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+var (
+	ErrNotLaunched    = errors.New("no ROM has been launched")
+	ErrNoProgram      = errors.New("launch needs a program")
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// threadID is the only thread, the SM83.
+const threadID = 1
+
+// Session is a debug adapter protocol session for one client, debugging a ROM
+// the client launches. Requests are read and answered one at a time, while
+// the Game Boy runs in the background between stops.
+type Session struct {
+	config *config.Config
+	in     *bufio.Reader
+
+	writeMu sync.Mutex // Serializes writes to out
+	out     io.Writer
+	seq     int
+
+	mu          sync.Mutex // Guards the machine and breakpoints, held by the Game Boy as it runs
+	machine     *machine.Machine
+	lines       *lineTable
+	stopOnEntry bool
+	breakpoints map[string][]placed       // Source breakpoints, by path
+	functions   []placed                  // Function breakpoints
+	locations   map[disasm.Location][]int // IDs of the breakpoints at each location
+	nextID      int
+
+	later []func() // Work to do once the current request has been answered
+
+	running sync.WaitGroup // Done when the Game Boy stops running
+	active  atomic.Bool    // Set while the Game Boy runs
+	pause   atomic.Bool    // Set to stop the Game Boy running
+	done    atomic.Bool    // Set once the client disconnects
+}
+
+// New creates a session reading requests from in and writing to out, with
+// cfg supplying the settings launch requests don't, such as the boot ROM.
+func New(cfg *config.Config, in io.Reader, out io.Writer) *Session {
+	return &Session{
+		config:      cfg,
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: make(map[string][]placed),
+		locations:   make(map[disasm.Location][]int),
+		nextID:      1,
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the input.
+func (s *Session) Serve() error {
+	defer s.stop()
+	for !s.done.Load() {
+		content, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			return fmt.Errorf("failed to parse request: %w", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+		s.handle(&req)
+	}
+	return nil
+}
+
+// send writes a response or event, numbering it.
+func (s *Session) send(message any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	switch m := message.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	if err := writeMessage(s.out, message); err != nil {
+		slog.Warn("Failed to write to debug adapter client", "error", err)
+	}
+}
+
+func (s *Session) respond(req *request, body any, err error) {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+		resp.Body = nil
+	}
+	s.send(resp)
+}
+
+func (s *Session) event(name string, body any) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// stopped tells the client the Game Boy has stopped.
+func (s *Session) stopped(reason, description string, breakpoints []int) {
+	s.event("stopped", stoppedBody{
+		Reason:            reason,
+		Description:       description,
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+		HitBreakpointIDs:  breakpoints,
+	})
+}
+
+// launch creates the Game Boy for a ROM and matches its source to it. The ROM
+// and symbols default to those in the config.
+func (s *Session) launch(args launchArguments) error {
+	if args.Program == "" {
+		args.Program = s.config.ROM
+	}
+	if args.Symbols == "" {
+		args.Symbols = s.config.Symbols
+	}
+	if args.Program == "" {
+		return ErrNoProgram
+	}
+	rom, err := os.ReadFile(args.Program)
+	if err != nil {
+		return fmt.Errorf("failed to read ROM: %w", err)
+	}
+	cart, err := cartridge.ParseCartridge(rom)
+	if err != nil {
+		return fmt.Errorf("failed to load cartridge: %w", err)
+	}
+	var bios []byte
+	if s.config.BIOS != "" {
+		bios, err = os.ReadFile(s.config.BIOS)
+		if err != nil {
+			return fmt.Errorf("failed to load BIOS: %w", err)
+		}
+	}
+	m, err := machine.NewWithBIOS(s.config, cart, bios)
+	if err != nil {
+		return err
+	}
+	table, err := symbols.Load(args.Program, args.Symbols)
+	if err != nil {
+		return err
+	}
+	m.SetSymbols(table)
+
+	lines := newLineTable(rom, table)
+	root := args.SourceRoot
+	if root == "" {
+		root = filepath.Dir(args.Program)
+	}
+	if table != nil {
+		if err := lines.scanDir(root); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.machine = m
+	s.lines = lines
+	s.stopOnEntry = args.StopOnEntry
+	return nil
+}
+
+// location returns where addr is in the ROM as mapped now.
+func (s *Session) location(addr uint16) disasm.Location {
+	loc := disasm.Location{Addr: addr}
+	if addr >= 0x4000 && addr < 0x8000 {
+		loc.Bank = s.machine.ROMBank()
+	}
+	return loc
+}
+
+// run runs the Game Boy in the background until done reports true after an
+// instruction, a breakpoint is hit or the client pauses it, then tells the
+// client why it stopped. A breakpoint at the PC it starts from is stepped
+// over.
+func (s *Session) run(reason string, done func() bool) {
+	if s.active.Swap(true) {
+		return
+	}
+	s.pause.Store(false)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		stop, description, hit := s.runLocked(reason, done)
+		s.active.Store(false)
+		if !s.done.Load() {
+			s.stopped(stop, description, hit)
+		}
+	}()
+}
+
+func (s *Session) runLocked(reason string, done func() bool) (stop, description string, hit []int) {
+	defer func() {
+		if r := recover(); r != nil {
+			stop, description, hit = "exception", fmt.Sprint(r), nil
+		}
+	}()
+	cycles := 0
+	for first := true; ; first = false {
+		if !first {
+			if ids := s.locations[s.location(s.machine.CPU.GetPC())]; len(ids) > 0 {
+				return "breakpoint", "", ids
+			}
+		}
+		cycles += s.machine.Step()
+		if done() {
+			return reason, "", nil
+		}
+		if s.pause.Load() {
+			return "pause", "", nil
+		}
+		if cycles >= consts.CyclesPerFrame {
+			// Let requests such as setBreakpoints in
+			cycles = 0
+			s.mu.Unlock()
+			s.mu.Lock()
+		}
+	}
+}
+
+// stop stops the Game Boy and waits for it, if it is running.
+func (s *Session) stop() {
+	s.pause.Store(true)
+	s.running.Wait()
+}
@@ -0,0 +1,96 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+)
+
+// The scopes of variables every frame has. They show the machine as it is
+// now, whichever frame is selected.
+const (
+	registersReference = iota + 1
+	ioReference
+)
+
+// ioRegisters names the IO registers in the order they are shown.
+//
+//nolint:gochecknoglobals
+var ioRegisters = []struct {
+	name string
+	addr uint16
+}{
+	{"P1", 0xFF00}, {"SB", 0xFF01}, {"SC", 0xFF02}, {"DIV", 0xFF04}, {"TIMA", 0xFF05},
+	{"TMA", 0xFF06}, {"TAC", 0xFF07}, {"IF", 0xFF0F},
+	{"NR10", 0xFF10}, {"NR11", 0xFF11}, {"NR12", 0xFF12}, {"NR13", 0xFF13}, {"NR14", 0xFF14},
+	{"NR21", 0xFF16}, {"NR22", 0xFF17}, {"NR23", 0xFF18}, {"NR24", 0xFF19},
+	{"NR30", 0xFF1A}, {"NR31", 0xFF1B}, {"NR32", 0xFF1C}, {"NR33", 0xFF1D}, {"NR34", 0xFF1E},
+	{"NR41", 0xFF20}, {"NR42", 0xFF21}, {"NR43", 0xFF22}, {"NR44", 0xFF23},
+	{"NR50", 0xFF24}, {"NR51", 0xFF25}, {"NR52", 0xFF26},
+	{"LCDC", 0xFF40}, {"STAT", 0xFF41}, {"SCY", 0xFF42}, {"SCX", 0xFF43}, {"LY", 0xFF44},
+	{"LYC", 0xFF45}, {"DMA", 0xFF46}, {"BGP", 0xFF47}, {"OBP0", 0xFF48}, {"OBP1", 0xFF49},
+	{"WY", 0xFF4A}, {"WX", 0xFF4B}, {"IE", 0xFFFF},
+}
+
+func scopes(_ *Session, _ json.RawMessage) (any, error) {
+	return scopesBody{Scopes: []scope{
+		{Name: "Registers", VariablesReference: registersReference},
+		{Name: "IO registers", VariablesReference: ioReference},
+	}}, nil
+}
+
+func variables(s *Session, raw json.RawMessage) (any, error) {
+	args, err := decode[variablesArguments](raw)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.machine == nil {
+		return nil, ErrNotLaunched
+	}
+	var vars []variable
+	switch args.VariablesReference {
+	case registersReference:
+		r := s.machine.CPU.Registers()
+		for _, reg := range []struct {
+			name  string
+			value byte
+		}{{"A", r.A}, {"F", r.F}, {"B", r.B}, {"C", r.C}, {"D", r.D}, {"E", r.E}, {"H", r.H}, {"L", r.L}} {
+			vars = append(vars, variable{Name: reg.name, Value: fmt.Sprintf("0x%02X", reg.value)})
+		}
+		for _, reg := range []struct {
+			name  string
+			value uint16
+		}{
+			{"AF", uint16(r.A)<<8 | uint16(r.F)}, {"BC", uint16(r.B)<<8 | uint16(r.C)},
+			{"DE", uint16(r.D)<<8 | uint16(r.E)}, {"HL", uint16(r.H)<<8 | uint16(r.L)},
+			{"SP", r.SP}, {"PC", r.PC},
+		} {
+			vars = append(vars, variable{Name: reg.name, Value: fmt.Sprintf("0x%04X", reg.value)})
+		}
+		vars = append(vars,
+			variable{Name: "Flags", Value: flags(r.F)},
+			variable{Name: "IME", Value: strconv.FormatBool(r.IME)},
+			variable{Name: "Halted", Value: strconv.FormatBool(s.machine.CPU.IsHalted())},
+		)
+	case ioReference:
+		for _, reg := range ioRegisters {
+			vars = append(vars, variable{Name: reg.name, Value: fmt.Sprintf("0x%02X", s.machine.Peek(reg.addr))})
+		}
+	}
+	return variablesBody{Variables: vars}, nil
+}
+
+// flags formats F as ZNHC, with a dash for each flag that is clear.
+func flags(f byte) string {
+	out := []byte("----")
+	for i, flag := range []cpu.Flag{cpu.ZeroFlag, cpu.NegativeFlag, cpu.HalfCarryFlag, cpu.CarryFlag} {
+		if f&byte(flag) != 0 {
+			out[i] = "ZNHC"[i]
+		}
+	}
+	return string(out)
+}
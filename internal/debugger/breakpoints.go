@@ -0,0 +1,167 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+)
+
+type breakpointKind int
+
+const (
+	kindBreak breakpointKind = iota // Stop before running an instruction
+	kindWatch                       // Stop after an instruction accesses memory
+)
+
+// anyBank matches an address whichever bank is mapped.
+const anyBank = -1
+
+type breakpoint struct {
+	id    int
+	kind  breakpointKind
+	bank  int // ROM bank the address must be in, or anyBank
+	addr  uint16
+	watch memory.Watchpoint // The machine's watchpoint, for kindWatch
+}
+
+func (bp *breakpoint) location() string {
+	if bp.bank == anyBank {
+		return fmt.Sprintf("0x%04X", bp.addr)
+	}
+	return fmt.Sprintf("%02X:%04X", bp.bank, bp.addr)
+}
+
+func (bp *breakpoint) String() string {
+	if bp.kind == kindWatch {
+		return "watch " + bp.watch.String()
+	}
+	return "break " + bp.location()
+}
+
+func (d *Debugger) addBreakpoint(bank int, addr uint16) *breakpoint {
+	bp := &breakpoint{id: d.nextID, kind: kindBreak, bank: bank, addr: addr}
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+func (d *Debugger) addWatchpoint(w memory.Watchpoint) *breakpoint {
+	bp := &breakpoint{id: d.nextID, kind: kindWatch, watch: d.machine.AddWatchpoint(w)}
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp
+}
+
+func (d *Debugger) removeBreakpoint(i int) {
+	if bp := d.breakpoints[i]; bp.kind == kindWatch {
+		d.machine.RemoveWatchpoint(bp.watch.ID)
+	}
+	d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+}
+
+// watchpointFor returns the breakpoint for one of the machine's watchpoints.
+func (d *Debugger) watchpointFor(id int) *breakpoint {
+	for _, bp := range d.breakpoints {
+		if bp.kind == kindWatch && bp.watch.ID == id {
+			return bp
+		}
+	}
+	return &breakpoint{kind: kindWatch}
+}
+
+// breakpointAt returns the breakpoint on the instruction at pc, if any.
+func (d *Debugger) breakpointAt(pc uint16) *breakpoint {
+	for _, bp := range d.breakpoints {
+		if bp.kind == kindBreak && bp.addr == pc && d.inBank(bp.bank, pc) {
+			return bp
+		}
+	}
+	return nil
+}
+
+// inBank reports whether addr is currently mapped from the given ROM bank.
+// Addresses outside ROM are in every bank.
+func (d *Debugger) inBank(bank int, addr uint16) bool {
+	switch {
+	case bank == anyBank || addr >= 0x8000:
+		return true
+	case addr < 0x4000:
+		return bank == 0
+	default:
+		return bank == d.machine.ROMBank()
+	}
+}
+
+// parseLocation parses a label or an address, optionally prefixed with a ROM
+// bank as in 01:4000.
+func (d *Debugger) parseLocation(s string) (int, uint16, error) {
+	if sym, ok := d.machine.Symbols().Find(s); ok {
+		if sym.Addr >= 0x4000 && sym.Addr < 0x8000 {
+			return sym.Bank, sym.Addr, nil
+		}
+		return anyBank, sym.Addr, nil
+	}
+	bankText, addrText, ok := strings.Cut(s, ":")
+	if !ok {
+		addr, err := parseAddress(s)
+		return anyBank, addr, err
+	}
+	bank, err := strconv.ParseUint(trimHex(bankText), 16, 8)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidAddress, s)
+	}
+	addr, err := parseAddress(addrText)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(bank), addr, nil
+}
+
+// symbolAddress replaces a label with its address in hex, leaving anything
+// else as it is.
+func (d *Debugger) symbolAddress(s string) string {
+	if sym, ok := d.machine.Symbols().Find(s); ok {
+		return fmt.Sprintf("%04X", sym.Addr)
+	}
+	return s
+}
+
+// parseAddress parses a label or an address.
+func (d *Debugger) parseAddress(s string) (uint16, error) {
+	if sym, ok := d.machine.Symbols().Find(s); ok {
+		return sym.Addr, nil
+	}
+	return parseAddress(s)
+}
+
+func parseAddress(s string) (uint16, error) {
+	addr, err := strconv.ParseUint(trimHex(s), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidAddress, s)
+	}
+	return uint16(addr), nil
+}
+
+func parseValue(s string) (uint16, error) {
+	value, err := strconv.ParseUint(trimHex(s), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidValue, s)
+	}
+	return uint16(value), nil
+}
+
+func parseCount(s string) (int, error) {
+	count, err := strconv.Atoi(s)
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCount, s)
+	}
+	return count, nil
+}
+
+// trimHex removes a 0x or $ prefix.
+func trimHex(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	return strings.TrimPrefix(s, "$")
+}
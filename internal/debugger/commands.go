@@ -0,0 +1,291 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+)
+
+type command struct {
+	names []string // The command's name followed by its aliases
+	usage string
+	help  string
+	run   func(d *Debugger, args []string) error
+}
+
+// commands lists every command in the order help shows them. help and quit
+// are handled by Debugger.Execute.
+//
+//nolint:gochecknoglobals
+var commands = []*command{
+	{names: []string{"help", "h"}, usage: "help", help: "List the commands"},
+	{names: []string{"regs", "r"}, usage: "regs", help: "Show the CPU registers", run: regs},
+	{names: []string{"step", "s"}, usage: "step [count]", help: "Run count instructions, 1 by default", run: step},
+	{names: []string{"next", "n"}, usage: "next", help: "Run an instruction, running whole subroutines called by CALL and RST", run: next},
+	{names: []string{"finish", "f"}, usage: "finish", help: "Run until the current subroutine returns", run: finish},
+	{names: []string{"backtrace", "bt"}, usage: "backtrace", help: "Show where the PC is and the calls that haven't returned, innermost first", run: backtrace},
+	{names: []string{"continue", "c"}, usage: "continue", help: "Run until a breakpoint, a watchpoint or control-C", run: cont},
+	{names: []string{"break", "b"}, usage: "break [addr|bank:addr]", help: "Stop before running the instruction at addr, or list breakpoints", run: breakCmd},
+	{names: []string{"watch", "w"}, usage: "watch r|w|x addr[-end] [cond]", help: "Stop after an instruction reads or writes addresses, or before one at them executes, if the byte matches a condition such as ==42", run: watch},
+	{names: []string{"delete", "d"}, usage: "delete [n]", help: "Delete breakpoint or watchpoint n, or all of them", run: deleteCmd},
+	{names: []string{"x"}, usage: "x/count addr", help: "Dump count bytes of memory from addr, 16 by default", run: examine},
+	{names: []string{"disas"}, usage: "disas [addr] [count]", help: "Disassemble count instructions from addr, the PC and 8 by default", run: disas},
+	{names: []string{"set"}, usage: "set reg=value", help: "Set a register: a, f, b, c, d, e, h, l, af, bc, de, hl, sp or pc", run: set},
+	{names: []string{"history"}, usage: "history", help: "List the commands run, to repeat with !n", run: history},
+	{names: []string{"quit", "q", "exit"}, usage: "quit", help: "Exit the debugger"},
+}
+
+func lookup(name string) (*command, bool) {
+	for _, cmd := range commands {
+		for _, n := range cmd.names {
+			if n == name {
+				return cmd, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (d *Debugger) help() {
+	for _, cmd := range commands {
+		fmt.Fprintf(d.out, "  %-32s %s\n", cmd.usage, cmd.help)
+	}
+	fmt.Fprintln(d.out, "Addresses and values are hex. An empty line repeats the last command.")
+}
+
+func regs(d *Debugger, _ []string) error {
+	fmt.Fprint(d.out, strings.TrimPrefix(d.machine.CPU.DebugRegisters(), "\n"))
+	fmt.Fprintf(d.out, "IME: %t, Halted: %t\n", d.machine.CPU.Registers().IME, d.machine.CPU.IsHalted())
+	return nil
+}
+
+func step(d *Debugger, args []string) error {
+	count := 1
+	if len(args) > 0 {
+		var err error
+		count, err = parseCount(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	d.run(func() bool {
+		count--
+		return count <= 0
+	})
+	return nil
+}
+
+// isCall reports whether the opcode calls a subroutine, conditionally or not.
+func isCall(opcode byte) bool {
+	switch opcode {
+	case 0xC4, 0xCC, 0xCD, 0xD4, 0xDC: // CALL
+		return true
+	}
+	return opcode&0xC7 == 0xC7 // RST
+}
+
+// isReturn reports whether the opcode returns from a subroutine, conditionally
+// or not.
+func isReturn(opcode byte) bool {
+	switch opcode {
+	case 0xC0, 0xC8, 0xC9, 0xD0, 0xD8, 0xD9: // RET, RETI
+		return true
+	}
+	return false
+}
+
+func next(d *Debugger, _ []string) error {
+	r := d.machine.CPU.Registers()
+	if !isCall(d.machine.Peek(r.PC)) {
+		return step(d, nil)
+	}
+	after := r.PC + uint16(disasm.Decode(d.machine.Peek, r.PC).Len())
+	d.run(func() bool {
+		// Recursive calls pass the same PC deeper in the stack
+		current := d.machine.CPU.Registers()
+		return current.PC == after && current.SP >= r.SP
+	})
+	return nil
+}
+
+func finish(d *Debugger, _ []string) error {
+	sp := d.machine.CPU.Registers().SP
+	opcode := d.machine.Peek(d.machine.CPU.GetPC())
+	d.run(func() bool {
+		returned := isReturn(opcode) && d.machine.CPU.Registers().SP > sp
+		opcode = d.machine.Peek(d.machine.CPU.GetPC())
+		return returned
+	})
+	return nil
+}
+
+func backtrace(d *Debugger, _ []string) error {
+	calls := d.machine.CPU.CallStack()
+	fmt.Fprintf(d.out, "#0  0x%04X%s\n", d.machine.CPU.GetPC(), d.symbolize(d.machine.CPU.GetPC()))
+	for i := len(calls) - 1; i >= 0; i-- {
+		suffix := ""
+		if calls[i].Interrupt {
+			suffix = " (interrupted)"
+		}
+		fmt.Fprintf(d.out, "#%d  0x%04X%s%s\n", len(calls)-i, calls[i].Site, d.symbolize(calls[i].Site), suffix)
+	}
+	return nil
+}
+
+func cont(d *Debugger, _ []string) error {
+	d.run(func() bool { return false })
+	return nil
+}
+
+func breakCmd(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints or watchpoints.")
+		}
+		for _, bp := range d.breakpoints {
+			fmt.Fprintf(d.out, "%d: %s\n", bp.id, bp)
+		}
+		return nil
+	}
+	bank, addr, err := d.parseLocation(args[0])
+	if err != nil {
+		return err
+	}
+	bp := d.addBreakpoint(bank, addr)
+	fmt.Fprintf(d.out, "Breakpoint %d at %s%s\n", bp.id, bp.location(), d.symbolize(addr))
+	return nil
+}
+
+func watch(d *Debugger, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%w: watch r|w|x addr[-end] [cond]", ErrUsage)
+	}
+	// Either end of the range may be a label
+	start, end, isRange := strings.Cut(args[1], "-")
+	addrs := d.symbolAddress(start)
+	if isRange {
+		addrs += "-" + d.symbolAddress(end)
+	}
+	w, err := memory.ParseWatchpoint(args[0], addrs, strings.Join(args[2:], ""))
+	if err != nil {
+		return err
+	}
+	bp := d.addWatchpoint(w)
+	fmt.Fprintf(d.out, "Watchpoint %d: %s\n", bp.id, bp.watch)
+	return nil
+}
+
+func deleteCmd(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		for len(d.breakpoints) > 0 {
+			d.removeBreakpoint(0)
+		}
+		return nil
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNoBreakpoint, args[0])
+	}
+	for i, bp := range d.breakpoints {
+		if bp.id == id {
+			d.removeBreakpoint(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d", ErrNoBreakpoint, id)
+}
+
+func examine(d *Debugger, args []string) error {
+	count := 16
+	if len(args) == 2 {
+		var err error
+		count, err = parseCount(args[0])
+		if err != nil {
+			return err
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%w: x/count addr", ErrUsage)
+	}
+	addr, err := d.parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i += 16 {
+		line := make([]string, 0, 16)
+		for j := i; j < min(i+16, count); j++ {
+			line = append(line, fmt.Sprintf("%02X", d.machine.Peek(addr+uint16(j))))
+		}
+		fmt.Fprintf(d.out, "0x%04X: %s\n", addr+uint16(i), strings.Join(line, " "))
+	}
+	return nil
+}
+
+func disas(d *Debugger, args []string) error {
+	addr := d.machine.CPU.GetPC()
+	count := 8
+	var err error
+	if len(args) > 0 {
+		addr, err = d.parseAddress(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	if len(args) > 1 {
+		count, err = parseCount(args[1])
+		if err != nil {
+			return err
+		}
+	}
+	for range count {
+		fmt.Fprintln(d.out, strings.TrimRight(d.disassemble(addr, true), " "))
+		addr += uint16(disasm.Decode(d.machine.Peek, addr).Len())
+	}
+	return nil
+}
+
+func set(d *Debugger, args []string) error {
+	name, value, ok := strings.Cut(strings.Join(args, ""), "=")
+	if !ok {
+		return fmt.Errorf("%w: set reg=value", ErrUsage)
+	}
+	v, err := parseValue(value)
+	if err != nil {
+		return err
+	}
+	r := d.machine.CPU.Registers()
+	pairs := map[string][2]*byte{"af": {&r.A, &r.F}, "bc": {&r.B, &r.C}, "de": {&r.D, &r.E}, "hl": {&r.H, &r.L}}
+	singles := map[string]*byte{"a": &r.A, "f": &r.F, "b": &r.B, "c": &r.C, "d": &r.D, "e": &r.E, "h": &r.H, "l": &r.L}
+	name = strings.ToLower(name)
+	switch {
+	case name == "sp":
+		r.SP = v
+	case name == "pc":
+		r.PC = v
+	case pairs[name] != [2]*byte{}:
+		*pairs[name][0], *pairs[name][1] = byte(v>>8), byte(v)
+	case singles[name] != nil:
+		if v > 0xFF {
+			return fmt.Errorf("%w: %s doesn't fit in %s", ErrInvalidValue, value, name)
+		}
+		*singles[name] = byte(v)
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidRegister, name)
+	}
+	// F's low nibble is always zero
+	r.F &= 0xF0
+	d.machine.CPU.SetRegisters(r)
+	d.printStop()
+	return nil
+}
+
+func history(d *Debugger, _ []string) error {
+	for i, line := range d.history {
+		fmt.Fprintf(d.out, "%4d  %s\n", i+1, line)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+)
+
+var (
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrInvalidCount    = errors.New("invalid count")
+	ErrInvalidRegister = errors.New("invalid register")
+	ErrNoBreakpoint    = errors.New("no such breakpoint")
+	ErrNoHistory       = errors.New("no such command in history")
+	ErrUsage           = errors.New("usage")
+)
+
+// Debugger drives a machine from a gdb-like command language. Addresses and
+// values are hex, with or without a 0x or $ prefix, and counts are decimal.
+type Debugger struct {
+	machine *machine.Machine
+	out     io.Writer
+
+	breakpoints []*breakpoint
+	nextID      int
+	history     []string
+
+	stop string // Why execution stopped early, if it did
+}
+
+// New creates a debugger for the machine that prints to out.
+func New(m *machine.Machine, out io.Writer) *Debugger {
+	return &Debugger{
+		machine: m,
+		out:     out,
+		nextID:  1,
+	}
+}
+
+// Run reads commands from in and runs them until quit or the end of input.
+func (d *Debugger) Run(in io.Reader) error {
+	fmt.Fprintln(d.out, `Type "help" for a list of commands.`)
+	d.printStop()
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(d.out, "(gb) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(d.out)
+			break
+		}
+		if d.Execute(scanner.Text()) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read command: %w", err)
+	}
+	return nil
+}
+
+// Execute runs a single command line and reports whether it asked to quit.
+// An empty line repeats the previous command and !n repeats the nth command
+// in the history.
+func (d *Debugger) Execute(line string) (quit bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "":
+		if len(d.history) == 0 {
+			return false
+		}
+		line = d.history[len(d.history)-1]
+	case strings.HasPrefix(line, "!"):
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 1 || n > len(d.history) {
+			fmt.Fprintf(d.out, "error: %v: %s\n", ErrNoHistory, line)
+			return false
+		}
+		line = d.history[n-1]
+		fmt.Fprintln(d.out, line)
+		d.history = append(d.history, line)
+	default:
+		d.history = append(d.history, line)
+	}
+
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	if strings.HasPrefix(name, "x/") {
+		name, args = "x", append([]string{name[2:]}, args...)
+	}
+	switch name {
+	case "quit", "exit", "q":
+		return true
+	case "help", "h":
+		d.help()
+		return false
+	}
+	cmd, ok := lookup(name)
+	if !ok {
+		fmt.Fprintf(d.out, "error: %v: %s\n", ErrUnknownCommand, name)
+		return false
+	}
+	if err := d.call(cmd, args); err != nil {
+		fmt.Fprintf(d.out, "error: %v\n", err)
+	}
+	return false
+}
+
+// call runs a command, turning a panic in the CPU into an error.
+func (d *Debugger) call(cmd *command, args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+			d.printStop()
+		}
+	}()
+	return cmd.run(d, args)
+}
+
+// run steps the machine until done reports true after an instruction, a
+// breakpoint or watchpoint is hit, or the user presses control-C. A
+// breakpoint at the PC it starts from is stepped over.
+func (d *Debugger) run(done func() bool) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	d.stop = ""
+	for first := true; ; first = false {
+		if !first {
+			if bp := d.breakpointAt(d.machine.CPU.GetPC()); bp != nil {
+				d.stop = fmt.Sprintf("Breakpoint %d at %s%s", bp.id, bp.location(), d.symbolize(bp.addr))
+				break
+			}
+		}
+		d.machine.Step()
+		if hit := d.machine.WatchHit(); hit != nil {
+			d.stop = fmt.Sprintf("Watchpoint %d: %s", d.watchpointFor(hit.Watchpoint.ID).id, hit)
+			break
+		}
+		if done() {
+			break
+		}
+		select {
+		case <-interrupt:
+			d.stop = "Interrupted"
+		default:
+		}
+		if d.stop != "" {
+			break
+		}
+	}
+	if d.stop != "" {
+		fmt.Fprintln(d.out, d.stop)
+	}
+	d.printStop()
+}
+
+// printStop prints the registers and the next instruction on one line.
+func (d *Debugger) printStop() {
+	r := d.machine.CPU.Registers()
+	fmt.Fprintf(d.out, "%s  A:%02X F:%s BC:%02X%02X DE:%02X%02X HL:%02X%02X SP:%04X\n",
+		d.disassemble(r.PC, false), r.A, flags(r.F), r.B, r.C, r.D, r.E, r.H, r.L, r.SP)
+}
+
+// disassemble formats the instruction at addr with its bytes, marking it if
+// it is the next to run.
+func (d *Debugger) disassemble(addr uint16, mark bool) string {
+	inst := disasm.Decode(d.machine.Peek, addr)
+	label := func(addr uint16) (string, bool) {
+		return d.machine.Symbols().Lookup(d.machine.ROMBank(), addr)
+	}
+	raw := make([]string, inst.Len())
+	for i, b := range inst.Bytes {
+		raw[i] = fmt.Sprintf("%02X", b)
+	}
+	prefix := ""
+	if mark {
+		prefix = "   "
+		if addr == d.machine.CPU.GetPC() {
+			prefix = "=> "
+		}
+	}
+	return fmt.Sprintf("%s0x%04X%s: %-9s %-16s", prefix, addr, d.symbolize(addr), strings.Join(raw, " "), inst.Format(label))
+}
+
+// symbolize names addr after the nearest label as in " <PlayerUpdate+3>", or
+// returns an empty string if there are no labels before it.
+func (d *Debugger) symbolize(addr uint16) string {
+	if name := d.machine.Symbolize(addr); name != "" {
+		return " <" + name + ">"
+	}
+	return ""
+}
+
+// flags formats F as ZNHC, with a dash for each flag that is clear.
+func flags(f byte) string {
+	out := []byte("----")
+	for i, flag := range []cpu.Flag{cpu.ZeroFlag, cpu.NegativeFlag, cpu.HalfCarryFlag, cpu.CarryFlag} {
+		if f&byte(flag) != 0 {
+			out[i] = "ZNHC"[i]
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,220 @@
+package debugger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/debugger"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+// program calls a subroutine that stores 0x42 to 0xC100, then increments
+// 0xC000 forever.
+//
+//nolint:gochecknoglobals
+var program = map[uint16][]byte{
+	0x0150: {
+		0x31, 0xFE, 0xFF, // LD SP,0xFFFE
+		0xCD, 0x60, 0x01, // CALL 0x0160
+		0x21, 0x00, 0xC0, // LD HL,0xC000
+		0x34,       // INC (HL)
+		0x18, 0xFD, // JR -3
+	},
+	0x0160: {
+		0x3E, 0x42, // LD A,0x42
+		0xEA, 0x00, 0xC1, // LD (0xC100),A
+		0xC9, // RET
+	},
+}
+
+func newMachine(t *testing.T) *machine.Machine {
+	t.Helper()
+
+	rom := make([]byte, 2*16384)
+	// Jump over the header to the program
+	copy(rom[0x100:], []byte{0x00, 0xC3, 0x50, 0x01}) // NOP; JP 0x0150
+	for addr, code := range program {
+		copy(rom[addr:], code)
+	}
+	cart, err := cartridge.ParseCartridge(rom)
+	if err != nil {
+		t.Fatalf("ParseCartridge() error = %v", err)
+	}
+	m, err := machine.New(&config.Config{LogLevel: config.LogLevelWarn}, cart)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		commands []string
+		symbols  string
+		want     []string // Lines the output must contain, in order
+		pc       uint16
+	}{
+		{
+			name:     "step",
+			commands: []string{"step 3"},
+			want:     []string{"0x0153: CD 60 01  call $0160"},
+			pc:       0x0153,
+		},
+		{
+			name:     "repeat",
+			commands: []string{"step", "", ""},
+			pc:       0x0153,
+		},
+		{
+			name:     "next over call",
+			commands: []string{"step 3", "next"},
+			want:     []string{"0x0156: 21 00 C0  ld hl, $c000"},
+			pc:       0x0156,
+		},
+		{
+			name:     "backtrace",
+			commands: []string{"step 5", "backtrace"},
+			symbols:  "00:0150 Main\n00:0160 Store\n",
+			want:     []string{"#0  0x0162 <Store+2>", "#1  0x0153 <Main+3>"},
+			pc:       0x0162,
+		},
+		{
+			name:     "finish",
+			commands: []string{"step 4", "finish"},
+			pc:       0x0156,
+		},
+		{
+			name:     "breakpoint",
+			commands: []string{"break 0159", "continue"},
+			want:     []string{"Breakpoint 1 at 0x0159", "Breakpoint 1 at 0x0159", "0x0159: 34"},
+			pc:       0x0159,
+		},
+		{
+			name:     "banked breakpoint",
+			commands: []string{"break 00:0160", "c"},
+			want:     []string{"Breakpoint 1 at 00:0160"},
+			pc:       0x0160,
+		},
+		{
+			name:     "watchpoint",
+			commands: []string{"watch w C100", "continue"},
+			want:     []string{"Watchpoint 1: wrote 0x42 to 0xC100 at PC 0x0162 (ld [$c100], a)"},
+			pc:       0x0165,
+		},
+		{
+			name:     "execute watchpoint",
+			commands: []string{"watch x 0160-0165", "continue"},
+			want:     []string{"Watchpoint 1: x 0x0160-0x0165", "Watchpoint 1: executed 0x0160 at PC 0x0160 (ld a, $42)"},
+			pc:       0x0160,
+		},
+		{
+			name:     "continue past execute watchpoint",
+			commands: []string{"watch x 0160-0165", "continue", "continue"},
+			want:     []string{"Watchpoint 1: executed 0x0160 at PC 0x0160", "Watchpoint 1: executed 0x0162 at PC 0x0162"},
+			pc:       0x0162,
+		},
+		{
+			name:     "conditional watchpoint",
+			commands: []string{"watch r C000 == 2", "continue"},
+			want:     []string{"Watchpoint 1: read 0x02 from 0xC000 at PC 0x0159 (inc [hl])"},
+			pc:       0x015A,
+		},
+		{
+			name:     "deleted watchpoint",
+			commands: []string{"watch w C100", "delete 1", "break 0156", "continue"},
+			want:     []string{"Breakpoint 2 at 0x0156"},
+			pc:       0x0156,
+		},
+		{
+			name:     "symbols",
+			commands: []string{"break StoreAnswer", "continue", "step", "watch r wAnswer", "x/1 wAnswer"},
+			symbols:  "; Comment\n00:0160 StoreAnswer\n00:c100 wAnswer\n",
+			want: []string{
+				"Breakpoint 1 at 0x0160 <StoreAnswer>",
+				"0x0160 <StoreAnswer>: 3E 42     ld a, $42",
+				"0x0162 <StoreAnswer+2>: EA 00 C1  ld [wAnswer], a",
+				"Watchpoint 2: r 0xC100",
+				"0xC100: 00",
+			},
+			pc: 0x0162,
+		},
+		{
+			name:     "examine",
+			commands: []string{"break 0156", "c", "x/2 $C100"},
+			want:     []string{"0xC100: 42 00"},
+			pc:       0x0156,
+		},
+		{
+			name:     "disassemble",
+			commands: []string{"disas 0x0160 3"},
+			want:     []string{"   0x0160: 3E 42     ld a, $42", "   0x0162: EA 00 C1  ld [$c100], a", "   0x0165: C9        ret"},
+			pc:       0x0100,
+		},
+		{
+			name:     "set",
+			commands: []string{"set pc=0160", "set hl = 1234", "set a=100"},
+			want:     []string{"HL:1234", "error: invalid value"},
+			pc:       0x0160,
+		},
+		{
+			name:     "history",
+			commands: []string{"step", "regs", "!1", "history"},
+			want:     []string{"   1  step", "   2  regs", "   3  step", "   4  history"},
+			pc:       0x0150,
+		},
+		{
+			name:     "help",
+			commands: []string{"help"},
+			want: []string{
+				"  help                             List the commands",
+				"  regs                             Show the CPU registers",
+				"  quit                             Exit the debugger",
+				"Addresses and values are hex.",
+			},
+			pc: 0x0100,
+		},
+		{
+			name:     "unknown",
+			commands: []string{"frobnicate"},
+			want:     []string{"error: unknown command: frobnicate"},
+			pc:       0x0100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newMachine(t)
+			if tt.symbols != "" {
+				table, err := symbols.Parse(strings.NewReader(tt.symbols))
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				m.SetSymbols(table)
+			}
+			var out bytes.Buffer
+			in := strings.NewReader(strings.Join(tt.commands, "\n") + "\nquit\n")
+			if err := debugger.New(m, &out).Run(in); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			rest := out.String()
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("output is missing %q:\n%s", want, out.String())
+				}
+				rest = rest[i+len(want):]
+			}
+			if got := m.CPU.GetPC(); got != tt.pc {
+				t.Errorf("PC = 0x%04X, want 0x%04X\n%s", got, tt.pc, out.String())
+			}
+		})
+	}
+}
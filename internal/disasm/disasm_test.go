@@ -0,0 +1,91 @@
+package disasm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		code []byte
+		want string
+		len  int
+	}{
+		{[]byte{0x00}, "nop", 1},
+		{[]byte{0x06, 0x12}, "ld b, $12", 2},
+		{[]byte{0x21, 0x34, 0x12}, "ld hl, $1234", 3},
+		{[]byte{0xEA, 0x00, 0xC1}, "ld [$c100], a", 3},
+		{[]byte{0xE0, 0x40}, "ldh [$ff40], a", 2},
+		{[]byte{0xF2}, "ldh a, [c]", 1},
+		{[]byte{0x2A}, "ld a, [hl+]", 1},
+		{[]byte{0x18, 0xFE}, "jr $0200", 2},
+		{[]byte{0x20, 0x05}, "jr nz, $0207", 2},
+		{[]byte{0xC3, 0x50, 0x01}, "jp $0150", 3},
+		{[]byte{0xDC, 0x00, 0x40}, "call c, $4000", 3},
+		{[]byte{0xE8, 0xFD}, "add sp, -3", 2},
+		{[]byte{0xF8, 0x05}, "ld hl, sp+5", 2},
+		{[]byte{0x76}, "halt", 1},
+		{[]byte{0x78}, "ld a, b", 1},
+		{[]byte{0x96}, "sub a, [hl]", 1},
+		{[]byte{0xFF}, "rst $38", 1},
+		{[]byte{0x10, 0x00}, "stop", 2},
+		{[]byte{0xCB, 0x37}, "swap a", 2},
+		{[]byte{0xCB, 0x7C}, "bit 7, h", 2},
+		{[]byte{0xCB, 0xFE}, "set 7, [hl]", 2},
+		{[]byte{0xD3}, "db $d3", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			t.Parallel()
+
+			read := func(addr uint16) byte { return tt.code[addr-0x0200] }
+			inst := disasm.Decode(read, 0x0200)
+			if got := inst.String(); got != tt.want {
+				t.Errorf("Decode() = %q, want %q", got, tt.want)
+			}
+			if inst.Len() != tt.len {
+				t.Errorf("Len() = %d, want %d", inst.Len(), tt.len)
+			}
+		})
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	t.Parallel()
+
+	rom := make([]byte, 2*16384)
+	for i := range 0x100 {
+		rom[i] = 0xFF
+	}
+	copy(rom[0x100:], []byte{0x00, 0xC3, 0x50, 0x01}) // nop; jp $0150
+	copy(rom[0x150:], []byte{
+		0xCD, 0x00, 0x40, // call $4000
+		0x18, 0xFB, // jr $0150
+	})
+	copy(rom[0x4000:], []byte{
+		0x28, 0x01, // jr z, $4003
+		0xC9,       // ret
+		0xC9,       // ret
+		0x01, 0x02, // Data that isn't reached
+	})
+
+	var out bytes.Buffer
+	if err := disasm.Disassemble(rom, nil).Write(&out); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{
+		"SECTION \"ROM Bank $000\", ROM0[$0000]\n\nRST_00:\n    rst $38\n",
+		"Entry:\n    nop\n    jp Jump_000_0150\n    ds 76, $00\n",
+		"Jump_000_0150:\n    call Call_001_4000\n    jr Jump_000_0150\n    ds ",
+		"SECTION \"ROM Bank $001\", ROMX[$4000], BANK[$1]\n\nCall_001_4000:\n    jr z, Jump_001_4003\n    ret\n\nJump_001_4003:\n    ret\n    db $01, $02\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
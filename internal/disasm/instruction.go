@@ -0,0 +1,272 @@
+package disasm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operand placeholders in the opcode table, replaced by the bytes that follow
+// the opcode:
+//
+//	n8     an 8-bit immediate
+//	n16    a 16-bit immediate
+//	a16    a 16-bit address of code, as jumped to by JP and CALL
+//	[a16]  a 16-bit address of data
+//	[a8]   a high page address of data, as used by LDH
+//	e8     a relative jump target
+//	s8     a signed 8-bit immediate
+//
+//nolint:gochecknoglobals
+var opcodes = [256]string{
+	"nop", "ld bc, n16", "ld [bc], a", "inc bc", "inc b", "dec b", "ld b, n8", "rlca",
+	"ld [a16], sp", "add hl, bc", "ld a, [bc]", "dec bc", "inc c", "dec c", "ld c, n8", "rrca",
+	"stop", "ld de, n16", "ld [de], a", "inc de", "inc d", "dec d", "ld d, n8", "rla",
+	"jr e8", "add hl, de", "ld a, [de]", "dec de", "inc e", "dec e", "ld e, n8", "rra",
+	"jr nz, e8", "ld hl, n16", "ld [hl+], a", "inc hl", "inc h", "dec h", "ld h, n8", "daa",
+	"jr z, e8", "add hl, hl", "ld a, [hl+]", "dec hl", "inc l", "dec l", "ld l, n8", "cpl",
+	"jr nc, e8", "ld sp, n16", "ld [hl-], a", "inc sp", "inc [hl]", "dec [hl]", "ld [hl], n8", "scf",
+	"jr c, e8", "add hl, sp", "ld a, [hl-]", "dec sp", "inc a", "dec a", "ld a, n8", "ccf",
+	0xC0: "ret nz", "pop bc", "jp nz, a16", "jp a16", "call nz, a16", "push bc", "add a, n8", "rst $00",
+	"ret z", "ret", "jp z, a16", "", "call z, a16", "call a16", "adc a, n8", "rst $08",
+	"ret nc", "pop de", "jp nc, a16", "", "call nc, a16", "push de", "sub a, n8", "rst $10",
+	"ret c", "reti", "jp c, a16", "", "call c, a16", "", "sbc a, n8", "rst $18",
+	"ldh [a8], a", "pop hl", "ldh [c], a", "", "", "push hl", "and a, n8", "rst $20",
+	"add sp, s8", "jp hl", "ld [a16], a", "", "", "", "xor a, n8", "rst $28",
+	"ldh a, [a8]", "pop af", "ldh a, [c]", "di", "", "push af", "or a, n8", "rst $30",
+	"ld hl, sp+s8", "ld sp, hl", "ld a, [a16]", "ei", "", "", "cp a, n8", "rst $38",
+}
+
+//nolint:gochecknoglobals
+var (
+	registers = [8]string{"b", "c", "d", "e", "h", "l", "[hl]", "a"}
+	alu       = [8]string{"add a,", "adc a,", "sub a,", "sbc a,", "and a,", "xor a,", "or a,", "cp a,"}
+	rotations = [8]string{"rlc", "rrc", "rl", "rr", "sla", "sra", "swap", "srl"}
+)
+
+//nolint:gochecknoinits
+func init() {
+	// 0x40-0xBF are the register to register loads and ALU operations
+	for op := 0x40; op < 0xC0; op++ {
+		src := registers[op&7]
+		switch {
+		case op == 0x76:
+			opcodes[op] = "halt"
+		case op < 0x80:
+			opcodes[op] = fmt.Sprintf("ld %s, %s", registers[op>>3&7], src)
+		default:
+			opcodes[op] = fmt.Sprintf("%s %s", alu[op>>3&7], src)
+		}
+	}
+}
+
+// cbOpcode formats an instruction prefixed by 0xCB.
+func cbOpcode(op byte) string {
+	reg := registers[op&7]
+	bit := op >> 3 & 7
+	switch op >> 6 {
+	case 0:
+		return fmt.Sprintf("%s %s", rotations[bit], reg)
+	case 1:
+		return fmt.Sprintf("bit %d, %s", bit, reg)
+	case 2:
+		return fmt.Sprintf("res %d, %s", bit, reg)
+	default:
+		return fmt.Sprintf("set %d, %s", bit, reg)
+	}
+}
+
+// Flow describes how an instruction affects the PC.
+type Flow int
+
+const (
+	FlowNext        Flow = iota // Runs the next instruction
+	FlowJump                    // Jumps to Target
+	FlowBranch                  // Jumps to Target or runs the next instruction
+	FlowCall                    // Calls Target, then runs the next instruction
+	FlowReturn                  // Returns, or jumps somewhere unknown
+	FlowMaybeReturn             // Returns or runs the next instruction
+)
+
+// OperandKind describes what an operand refers to.
+type OperandKind int
+
+const (
+	OperandPlain OperandKind = iota // A register, condition or immediate
+	OperandCode                     // An address jumped or called to
+	OperandData                     // An address read or written in brackets
+)
+
+// Operand is an operand of an instruction.
+type Operand struct {
+	Kind OperandKind
+	Text string // The operand as written, with addresses in hex
+	Addr uint16 // The address it refers to, for OperandCode and OperandData
+}
+
+// Instruction is a decoded SM83 instruction.
+type Instruction struct {
+	Addr     uint16
+	Bytes    []byte
+	Mnemonic string
+	Operands []Operand
+	Flow     Flow
+}
+
+// Decode decodes the instruction at addr, reading its bytes with read.
+// Opcodes the SM83 doesn't have decode as a single byte of data.
+func Decode(read func(addr uint16) byte, addr uint16) Instruction {
+	op := read(addr)
+	inst := Instruction{Addr: addr, Bytes: []byte{op}}
+	text := opcodes[op]
+	switch {
+	case op == 0xCB:
+		cb := read(addr + 1)
+		inst.Bytes = append(inst.Bytes, cb)
+		text = cbOpcode(cb)
+	case op == 0x10 && read(addr+1) == 0x00:
+		// STOP is followed by a padding byte that RGBDS emits for it
+		inst.Bytes = append(inst.Bytes, 0x00)
+	case text == "" || op == 0x10:
+		return Data(addr, op)
+	}
+
+	mnemonic, operands, _ := strings.Cut(text, " ")
+	inst.Mnemonic = mnemonic
+	if operands != "" {
+		for _, operand := range strings.Split(operands, ", ") {
+			inst.Operands = append(inst.Operands, inst.decodeOperand(read, operand))
+		}
+	}
+	if op&0xC7 == 0xC7 {
+		// RST calls a fixed vector
+		inst.Operands[0] = Operand{Kind: OperandCode, Text: inst.Operands[0].Text, Addr: uint16(op & 0x38)}
+	}
+	inst.Flow = flow(op, len(inst.Operands))
+	return inst
+}
+
+// Data returns a single byte of data as an instruction, for bytes that aren't
+// code.
+func Data(addr uint16, b byte) Instruction {
+	return Instruction{
+		Addr:     addr,
+		Bytes:    []byte{b},
+		Mnemonic: "db",
+		Operands: []Operand{{Text: fmt.Sprintf("$%02x", b)}},
+	}
+}
+
+// decodeOperand replaces the placeholder in an operand with the bytes that
+// follow the instruction's, appending them to its bytes.
+func (i *Instruction) decodeOperand(read func(addr uint16) byte, operand string) Operand {
+	next := func() byte {
+		b := read(i.Addr + uint16(len(i.Bytes)))
+		i.Bytes = append(i.Bytes, b)
+		return b
+	}
+	word := func() uint16 {
+		lo := next()
+		return uint16(next())<<8 | uint16(lo)
+	}
+	switch operand {
+	case "n8":
+		return Operand{Text: fmt.Sprintf("$%02x", next())}
+	case "n16":
+		return Operand{Text: fmt.Sprintf("$%04x", word())}
+	case "a16":
+		addr := word()
+		return Operand{Kind: OperandCode, Text: fmt.Sprintf("$%04x", addr), Addr: addr}
+	case "[a16]":
+		addr := word()
+		return Operand{Kind: OperandData, Text: fmt.Sprintf("[$%04x]", addr), Addr: addr}
+	case "[a8]":
+		addr := 0xFF00 | uint16(next())
+		return Operand{Kind: OperandData, Text: fmt.Sprintf("[$%04x]", addr), Addr: addr}
+	case "e8":
+		offset := int8(next())
+		addr := i.Addr + uint16(len(i.Bytes)) + uint16(offset)
+		return Operand{Kind: OperandCode, Text: fmt.Sprintf("$%04x", addr), Addr: addr}
+	case "s8":
+		return Operand{Text: strconv.Itoa(int(int8(next())))}
+	case "sp+s8":
+		offset := int8(next())
+		if offset < 0 {
+			return Operand{Text: fmt.Sprintf("sp%d", offset)}
+		}
+		return Operand{Text: fmt.Sprintf("sp+%d", offset)}
+	}
+	return Operand{Text: operand}
+}
+
+func flow(op byte, operands int) Flow {
+	conditional := operands > 1
+	switch {
+	case op == 0x18 || op == 0x20 || op == 0x28 || op == 0x30 || op == 0x38, // JR
+		op == 0xC2 || op == 0xC3 || op == 0xCA || op == 0xD2 || op == 0xDA: // JP
+		if conditional {
+			return FlowBranch
+		}
+		return FlowJump
+	case op == 0xC4 || op == 0xCC || op == 0xCD || op == 0xD4 || op == 0xDC, // CALL
+		op&0xC7 == 0xC7: // RST
+		return FlowCall
+	case op == 0xC9 || op == 0xD9 || op == 0xE9: // RET, RETI, JP HL
+		return FlowReturn
+	case op == 0xC0 || op == 0xC8 || op == 0xD0 || op == 0xD8:
+		return FlowMaybeReturn
+	}
+	return FlowNext
+}
+
+// Len returns the instruction's length in bytes.
+func (i Instruction) Len() int {
+	return len(i.Bytes)
+}
+
+// Target returns the address the instruction jumps or calls to, if it's known.
+func (i Instruction) Target() (uint16, bool) {
+	if i.Flow != FlowJump && i.Flow != FlowBranch && i.Flow != FlowCall {
+		return 0, false
+	}
+	for _, operand := range i.Operands {
+		if operand.Kind == OperandCode {
+			return operand.Addr, true
+		}
+	}
+	return 0, false
+}
+
+// FallsThrough reports whether the instruction can be followed by the one
+// after it.
+func (i Instruction) FallsThrough() bool {
+	return i.Flow != FlowJump && i.Flow != FlowReturn
+}
+
+// String formats the instruction in RGBDS syntax, such as ld [$c100], a.
+func (i Instruction) String() string {
+	return i.Format(nil)
+}
+
+// Format formats the instruction in RGBDS syntax, writing addresses as the
+// names label returns for them, if any.
+func (i Instruction) Format(label func(addr uint16) (string, bool)) string {
+	if len(i.Operands) == 0 {
+		return i.Mnemonic
+	}
+	operands := make([]string, len(i.Operands))
+	for n, operand := range i.Operands {
+		operands[n] = operand.Text
+		// RGBDS needs RST's vector to be a constant
+		if label == nil || operand.Kind == OperandPlain || i.Mnemonic == "rst" {
+			continue
+		}
+		if name, ok := label(operand.Addr); ok {
+			operands[n] = name
+			if operand.Kind == OperandData {
+				operands[n] = "[" + name + "]"
+			}
+		}
+	}
+	return i.Mnemonic + " " + strings.Join(operands, ", ")
+}
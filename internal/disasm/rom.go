@@ -0,0 +1,356 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+// entryPoints are where the CPU starts running a ROM: the RST vectors, the
+// interrupt vectors and the entry point after the boot ROM.
+//
+//nolint:gochecknoglobals
+var entryPoints = []struct {
+	addr uint16
+	name string
+}{
+	{0x0000, "RST_00"}, {0x0008, "RST_08"}, {0x0010, "RST_10"}, {0x0018, "RST_18"},
+	{0x0020, "RST_20"}, {0x0028, "RST_28"}, {0x0030, "RST_30"}, {0x0038, "RST_38"},
+	{0x0040, "VBlankInterrupt"}, {0x0048, "STATInterrupt"}, {0x0050, "TimerInterrupt"},
+	{0x0058, "SerialInterrupt"}, {0x0060, "JoypadInterrupt"},
+	{0x0100, "Entry"},
+}
+
+// EntryPoint returns the name given to the RST vector, interrupt vector or
+// entry point at addr, if there is one.
+func EntryPoint(addr uint16) (string, bool) {
+	for _, entry := range entryPoints {
+		if entry.addr == addr {
+			return entry.name, true
+		}
+	}
+	return "", false
+}
+
+// Location is an address in a ROM bank. Bank 0 is mapped at 0x0000-0x3FFF
+// and every other bank at 0x4000-0x7FFF.
+type Location struct {
+	Bank int
+	Addr uint16
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%02X:%04X", l.Bank, l.Addr)
+}
+
+// ROM is a ROM disassembled by following the code reachable from its entry
+// points. Bytes that aren't reached are taken to be data.
+type ROM struct {
+	data    []byte
+	banks   int
+	code    map[Location]Instruction
+	targets map[Location]Location // Where each jump or call goes, if known
+	labels  map[Location]string
+	equates map[uint16]string // Names of addresses outside ROM
+}
+
+// Disassemble disassembles a ROM, naming addresses after the labels in table,
+// which may be nil. Jumps from bank 0 into 0x4000-0x7FFF are followed into the
+// bank last selected by writing a constant to 0x2000-0x3FFF, or into bank 1
+// if the ROM doesn't bank.
+func Disassemble(rom []byte, table *symbols.Table) *ROM {
+	r := &ROM{
+		data:    rom,
+		banks:   (len(rom) + consts.ROMBankSize - 1) / consts.ROMBankSize,
+		code:    make(map[Location]Instruction),
+		targets: make(map[Location]Location),
+		labels:  make(map[Location]string),
+		equates: make(map[uint16]string),
+	}
+	var queue []Location
+	for _, entry := range entryPoints {
+		loc := Location{Addr: entry.addr}
+		if r.inROM(loc) {
+			r.labels[loc] = entry.name
+			queue = append(queue, loc)
+		}
+	}
+	for _, sym := range table.Symbols() {
+		loc := Location{Bank: sym.Bank, Addr: sym.Addr}
+		switch {
+		case sym.Addr >= 0x8000:
+			// Local labels can't be constants
+			if _, ok := r.equates[sym.Addr]; !ok && !strings.Contains(sym.Name, ".") {
+				r.equates[sym.Addr] = sym.Name
+			}
+		case sym.Addr < 0x4000:
+			loc.Bank = 0
+			fallthrough
+		default:
+			if r.inROM(loc) {
+				r.labels[loc] = sym.Name
+			}
+		}
+	}
+	for len(queue) > 0 {
+		loc := queue[len(queue)-1]
+		queue = r.trace(loc, queue[:len(queue)-1])
+	}
+	return r
+}
+
+// trace decodes the instructions run from loc until one that doesn't fall
+// through, adding the targets of its jumps and calls to the queue.
+func (r *ROM) trace(loc Location, queue []Location) []Location {
+	selected := 0 // Bank selected by the code so far, if known
+	a := -1       // Constant loaded into A, if known
+	for r.inROM(loc) {
+		if _, ok := r.code[loc]; ok {
+			break
+		}
+		inst := Decode(r.reader(loc.Bank), loc.Addr)
+		end := Location{Bank: loc.Bank, Addr: loc.Addr + uint16(inst.Len()) - 1}
+		if !r.inROM(end) || end.Addr < loc.Addr {
+			break
+		}
+		r.code[loc] = inst
+
+		switch op := inst.Bytes[0]; {
+		case op == 0x3E: // LD A,n8
+			a = int(inst.Bytes[1])
+		case op == 0xEA && inst.Operands[0].Addr >= 0x2000 && inst.Operands[0].Addr < 0x4000 && a >= 0:
+			selected = max(a, 1)
+		}
+
+		if addr, ok := inst.Target(); ok {
+			if target, ok := r.locate(loc.Bank, selected, addr); ok {
+				r.targets[loc] = target
+				r.label(target, inst.Flow)
+				queue = append(queue, target)
+			}
+		}
+		if !inst.FallsThrough() {
+			break
+		}
+		loc.Addr += uint16(inst.Len())
+	}
+	return queue
+}
+
+// locate finds the bank an address jumped to from code in a bank is in.
+func (r *ROM) locate(from, selected int, addr uint16) (Location, bool) {
+	loc := Location{Addr: addr}
+	switch {
+	case addr < 0x4000:
+	case from != 0:
+		loc.Bank = from
+	case selected != 0:
+		loc.Bank = selected
+	case r.banks == 2:
+		loc.Bank = 1
+	default:
+		return loc, false
+	}
+	return loc, r.inROM(loc)
+}
+
+// label names a jump or call target, unless it already has a name other than
+// a jump's.
+func (r *ROM) label(loc Location, flow Flow) {
+	name, ok := r.labels[loc]
+	if ok && (flow != FlowCall || !strings.HasPrefix(name, "Jump_")) {
+		return
+	}
+	prefix := "Jump"
+	if flow == FlowCall {
+		prefix = "Call"
+	}
+	r.labels[loc] = fmt.Sprintf("%s_%03X_%04X", prefix, loc.Bank, loc.Addr)
+}
+
+func (r *ROM) inROM(loc Location) bool {
+	if loc.Bank == 0 && loc.Addr >= 0x4000 || loc.Bank != 0 && (loc.Addr < 0x4000 || loc.Addr >= 0x8000) {
+		return false
+	}
+	return loc.Bank < r.banks && r.offset(loc) < len(r.data)
+}
+
+func (r *ROM) offset(loc Location) int {
+	if loc.Bank == 0 {
+		return int(loc.Addr)
+	}
+	return loc.Bank*consts.ROMBankSize + int(loc.Addr) - 0x4000
+}
+
+// reader returns a function that reads addresses as mapped with bank
+// selected, returning 0xFF past the end of the ROM.
+func (r *ROM) reader(bank int) func(addr uint16) byte {
+	return func(addr uint16) byte {
+		loc := Location{Addr: addr}
+		if addr >= 0x4000 {
+			loc.Bank = max(bank, 1)
+		}
+		if !r.inROM(loc) {
+			return 0xFF
+		}
+		return r.data[r.offset(loc)]
+	}
+}
+
+// Instruction returns the instruction decoded at a location, if it was
+// reached as code.
+func (r *ROM) Instruction(loc Location) (Instruction, bool) {
+	inst, ok := r.code[loc]
+	return inst, ok
+}
+
+// Label returns the name given to a location, if any.
+func (r *ROM) Label(loc Location) (string, bool) {
+	name, ok := r.labels[loc]
+	return name, ok
+}
+
+// Labels returns every labelled location in order.
+func (r *ROM) Labels() []Location {
+	locs := make([]Location, 0, len(r.labels))
+	for loc := range r.labels {
+		locs = append(locs, loc)
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		return locs[i].Bank < locs[j].Bank || locs[i].Bank == locs[j].Bank && locs[i].Addr < locs[j].Addr
+	})
+	return locs
+}
+
+// Write writes the ROM as RGBDS assembly, with a section for each bank, that
+// assembles back to the same bytes.
+func (r *ROM) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	addrs := make([]int, 0, len(r.equates))
+	for addr := range r.equates {
+		addrs = append(addrs, int(addr))
+	}
+	sort.Ints(addrs)
+	for _, addr := range addrs {
+		fmt.Fprintf(out, "DEF %s EQU $%04x\n", r.equates[uint16(addr)], addr)
+	}
+	if len(addrs) > 0 {
+		fmt.Fprintln(out)
+	}
+	for bank := range r.banks {
+		if bank == 0 {
+			fmt.Fprintf(out, "SECTION \"ROM Bank $%03X\", ROM0[$0000]\n", bank)
+		} else {
+			fmt.Fprintf(out, "\nSECTION \"ROM Bank $%03X\", ROMX[$4000], BANK[$%X]\n", bank, bank)
+		}
+		r.writeBank(out, bank)
+	}
+	return out.Flush()
+}
+
+func (r *ROM) writeBank(out *bufio.Writer, bank int) {
+	loc := Location{Bank: bank, Addr: 0x0000}
+	if bank != 0 {
+		loc.Addr = 0x4000
+	}
+	var data []byte
+	flush := func() {
+		writeData(out, data)
+		data = data[:0]
+	}
+	for r.inROM(loc) {
+		if name, ok := r.labels[loc]; ok {
+			flush()
+			fmt.Fprintf(out, "\n%s:\n", name)
+		}
+		inst, ok := r.code[loc]
+		if ok && !r.overlapped(loc, inst) {
+			flush()
+			fmt.Fprintf(out, "    %s\n", inst.Format(r.labelFor(loc)))
+			loc.Addr += uint16(inst.Len())
+			continue
+		}
+		data = append(data, r.data[r.offset(loc)])
+		loc.Addr++
+	}
+	flush()
+}
+
+// overlapped reports whether another instruction or label starts inside the
+// instruction at loc, in which case its bytes are written as data instead.
+func (r *ROM) overlapped(loc Location, inst Instruction) bool {
+	for i := 1; i < inst.Len(); i++ {
+		inside := Location{Bank: loc.Bank, Addr: loc.Addr + uint16(i)}
+		if _, ok := r.code[inside]; ok {
+			return true
+		}
+		if _, ok := r.labels[inside]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// labelFor returns a function naming the addresses the instruction at loc
+// refers to.
+func (r *ROM) labelFor(loc Location) func(addr uint16) (string, bool) {
+	return func(addr uint16) (string, bool) {
+		if target, ok := r.targets[loc]; ok && target.Addr == addr {
+			return r.Label(target)
+		}
+		switch {
+		case addr >= 0x8000:
+			name, ok := r.equates[addr]
+			return name, ok
+		case addr < 0x4000:
+			return r.Label(Location{Addr: addr})
+		case loc.Bank != 0:
+			return r.Label(Location{Bank: loc.Bank, Addr: addr})
+		}
+		return "", false
+	}
+}
+
+// writeData writes bytes as db lines of up to 8 bytes, and runs of 16 or more
+// of the same byte as ds.
+func writeData(out *bufio.Writer, data []byte) {
+	for len(data) > 0 {
+		run := 1
+		for run < len(data) && data[run] == data[0] {
+			run++
+		}
+		if run >= 16 {
+			fmt.Fprintf(out, "    ds %d, $%02x\n", run, data[0])
+			data = data[run:]
+			continue
+		}
+		line := data[:min(8, len(data))]
+		for i := 1; i < len(line); i++ {
+			// Start a new line for a run that will be written with ds
+			if rest := data[i:]; len(rest) >= 16 && allSame(rest[:16]) {
+				line = line[:i]
+				break
+			}
+		}
+		values := make([]string, len(line))
+		for i, b := range line {
+			values[i] = fmt.Sprintf("$%02x", b)
+		}
+		fmt.Fprintf(out, "    db %s\n", strings.Join(values, ", "))
+		data = data[len(line):]
+	}
+}
+
+func allSame(data []byte) bool {
+	for _, b := range data[1:] {
+		if b != data[0] {
+			return false
+		}
+	}
+	return true
+}
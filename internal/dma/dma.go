@@ -0,0 +1,79 @@
+package dma
+
+import (
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+// Reader reads the memory a transfer copies from.
+type Reader interface {
+	Read8(addr uint16) (uint8, error)
+}
+
+// DMA is the OAM DMA controller at 0xFF46. Writing a page number copies the
+// 160 bytes from that page to OAM, one byte per M-cycle.
+type DMA struct {
+	register byte // Last page written to 0xFF46
+	active   bool
+	index    int // Next byte to copy
+
+	memory    Reader
+	oam       *[consts.OAMSize]byte
+	scheduler *scheduler.Scheduler
+}
+
+func NewDMA(memory Reader, oam *[consts.OAMSize]byte, scheduler *scheduler.Scheduler) *DMA {
+	dma := &DMA{
+		memory:    memory,
+		oam:       oam,
+		scheduler: scheduler,
+	}
+	dma.Reset()
+	scheduler.Register(dma)
+	return dma
+}
+
+func (d *DMA) Reset() {
+	d.register = 0
+	d.active = false
+	d.index = 0
+}
+
+// Active reports whether a transfer is in progress.
+func (d *DMA) Active() bool {
+	return d.active
+}
+
+func (d *DMA) Read8(_ uint16) uint8 {
+	return d.register
+}
+
+func (d *DMA) Write8(_ uint16, data uint8) {
+	d.scheduler.Sync(d)
+	d.register = data
+	d.active = true
+	d.index = 0
+	d.scheduler.Wake(d)
+}
+
+// Sync copies a byte for every M-cycle elapsed during a transfer.
+func (d *DMA) Sync(elapsed uint64) uint64 {
+	if !d.active {
+		return scheduler.Never
+	}
+	source := uint16(d.register) << 8
+	for range elapsed / scheduler.TCyclesPerMCycle {
+		data, err := d.memory.Read8(source + uint16(d.index))
+		if err != nil {
+			// Nothing drives the bus, so it reads high
+			data = 0xFF
+		}
+		d.oam[d.index] = data
+		d.index++
+		if d.index == consts.OAMSize {
+			d.active = false
+			return scheduler.Never
+		}
+	}
+	return scheduler.TCyclesPerMCycle
+}
@@ -0,0 +1,44 @@
+package dma_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/dma"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+// pattern is memory where every byte holds the low byte of its address.
+type pattern struct{}
+
+func (pattern) Read8(addr uint16) (uint8, error) {
+	return byte(addr), nil
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	var oam [consts.OAMSize]byte
+	s := scheduler.NewScheduler()
+	d := dma.NewDMA(pattern{}, &oam, s)
+
+	d.Write8(0xFF46, 0xC1)
+	for range consts.OAMSize - 1 {
+		s.Tick()
+	}
+	if !d.Active() {
+		t.Fatal("transfer finished early")
+	}
+	s.Tick()
+	if d.Active() {
+		t.Fatal("transfer still active after 160 M-cycles")
+	}
+	for i, b := range oam {
+		if b != byte(i) {
+			t.Fatalf("OAM[%d] = 0x%02X, want 0x%02X", i, b, byte(i))
+		}
+	}
+	if reg := d.Read8(0xFF46); reg != 0xC1 {
+		t.Errorf("DMA register = 0x%02X, want 0xC1", reg)
+	}
+}
@@ -0,0 +1,40 @@
+package dma
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// dmaSnapshot is the DMA state stored in save states.
+type dmaSnapshot struct {
+	Register byte
+	Active   bool
+	Index    uint8
+}
+
+// SaveState writes the DMA state to w.
+func (d *DMA) SaveState(w io.Writer) error {
+	d.scheduler.Sync(d)
+	snapshot := dmaSnapshot{
+		Register: d.register,
+		Active:   d.active,
+		Index:    uint8(d.index),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write DMA state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores DMA state previously written with SaveState.
+func (d *DMA) LoadState(r io.Reader) error {
+	var snapshot dmaSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read DMA state: %w", err)
+	}
+	d.register = snapshot.Register
+	d.active = snapshot.Active
+	d.index = int(snapshot.Index)
+	return nil
+}
@@ -0,0 +1,235 @@
+package emulator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	ebiten "github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+//nolint:gochecknoglobals
+var standardGamepadButtons = map[string]ebiten.StandardGamepadButton{
+	"rightbottom":      ebiten.StandardGamepadButtonRightBottom,
+	"rightright":       ebiten.StandardGamepadButtonRightRight,
+	"rightleft":        ebiten.StandardGamepadButtonRightLeft,
+	"righttop":         ebiten.StandardGamepadButtonRightTop,
+	"fronttopleft":     ebiten.StandardGamepadButtonFrontTopLeft,
+	"fronttopright":    ebiten.StandardGamepadButtonFrontTopRight,
+	"frontbottomleft":  ebiten.StandardGamepadButtonFrontBottomLeft,
+	"frontbottomright": ebiten.StandardGamepadButtonFrontBottomRight,
+	"centerleft":       ebiten.StandardGamepadButtonCenterLeft,
+	"centerright":      ebiten.StandardGamepadButtonCenterRight,
+	"leftstick":        ebiten.StandardGamepadButtonLeftStick,
+	"rightstick":       ebiten.StandardGamepadButtonRightStick,
+	"lefttop":          ebiten.StandardGamepadButtonLeftTop,
+	"leftbottom":       ebiten.StandardGamepadButtonLeftBottom,
+	"leftleft":         ebiten.StandardGamepadButtonLeftLeft,
+	"leftright":        ebiten.StandardGamepadButtonLeftRight,
+	"centercenter":     ebiten.StandardGamepadButtonCenterCenter,
+}
+
+// keyBinding is a keyboard key that may be left unbound.
+type keyBinding struct {
+	key   ebiten.Key
+	bound bool
+}
+
+func parseKey(name string) (keyBinding, error) {
+	if name == "" {
+		return keyBinding{}, nil
+	}
+	var key ebiten.Key
+	if err := key.UnmarshalText([]byte(name)); err != nil {
+		return keyBinding{}, fmt.Errorf("invalid key %q: %w", name, err)
+	}
+	return keyBinding{key: key, bound: true}, nil
+}
+
+func (k keyBinding) pressed() bool {
+	return k.bound && ebiten.IsKeyPressed(k.key)
+}
+
+func (k keyBinding) justPressed() bool {
+	return k.bound && inpututil.IsKeyJustPressed(k.key)
+}
+
+func (k keyBinding) pressDuration() int {
+	if !k.bound {
+		return 0
+	}
+	return inpututil.KeyPressDuration(k.key)
+}
+
+type buttonKey struct {
+	button input.Button
+	key    keyBinding
+}
+
+type buttonGamepad struct {
+	button  input.Button
+	gamepad ebiten.StandardGamepadButton
+}
+
+type hotkeys struct {
+	frameStep keyBinding
+	resume    keyBinding
+	halt      keyBinding
+	reset     keyBinding
+	saveState keyBinding
+	loadState keyBinding
+	prevSlot  keyBinding
+	nextSlot  keyBinding
+	rewind    keyBinding
+
+	fastForward keyBinding
+	slowMotion  keyBinding
+}
+
+type bindings struct {
+	keys          []buttonKey
+	gamepad       []buttonGamepad
+	gamepadPlayer int
+	hotkeys       hotkeys
+	gamepadIDs    []ebiten.GamepadID
+}
+
+func newBindings(cfg *config.Config) (*bindings, error) {
+	b := &bindings{
+		gamepadPlayer: cfg.Gamepad.Player,
+	}
+
+	keys := []struct {
+		button input.Button
+		name   string
+	}{
+		{input.ButtonRight, cfg.Keys.Right},
+		{input.ButtonLeft, cfg.Keys.Left},
+		{input.ButtonUp, cfg.Keys.Up},
+		{input.ButtonDown, cfg.Keys.Down},
+		{input.ButtonA, cfg.Keys.A},
+		{input.ButtonB, cfg.Keys.B},
+		{input.ButtonSelect, cfg.Keys.Select},
+		{input.ButtonStart, cfg.Keys.Start},
+	}
+	// Keys are compared once parsed, as names such as "down" and "arrowdown"
+	// are the same key
+	seenKeys := make(map[ebiten.Key]string)
+	parse := func(name string) (keyBinding, error) {
+		key, err := parseKey(name)
+		if err != nil || !key.bound {
+			return key, err
+		}
+		if first, ok := seenKeys[key.key]; ok {
+			return keyBinding{}, fmt.Errorf("%w: %s and %s", config.ErrDuplicateKeyBinding, first, name)
+		}
+		seenKeys[key.key] = name
+		return key, nil
+	}
+	for _, k := range keys {
+		key, err := parse(k.name)
+		if err != nil {
+			return nil, err
+		}
+		if key.bound {
+			b.keys = append(b.keys, buttonKey{k.button, key})
+		}
+	}
+
+	gamepad := []struct {
+		button input.Button
+		name   string
+	}{
+		{input.ButtonRight, cfg.Gamepad.Right},
+		{input.ButtonLeft, cfg.Gamepad.Left},
+		{input.ButtonUp, cfg.Gamepad.Up},
+		{input.ButtonDown, cfg.Gamepad.Down},
+		{input.ButtonA, cfg.Gamepad.A},
+		{input.ButtonB, cfg.Gamepad.B},
+		{input.ButtonSelect, cfg.Gamepad.Select},
+		{input.ButtonStart, cfg.Gamepad.Start},
+	}
+	seenButtons := make(map[ebiten.StandardGamepadButton]string)
+	for _, g := range gamepad {
+		if g.name == "" {
+			continue
+		}
+		button, ok := standardGamepadButtons[strings.ToLower(g.name)]
+		if !ok {
+			return nil, fmt.Errorf("invalid gamepad button %q", g.name)
+		}
+		if first, ok := seenButtons[button]; ok {
+			return nil, fmt.Errorf("%w: %s and %s", config.ErrDuplicateGamepadBinding, first, g.name)
+		}
+		seenButtons[button] = g.name
+		b.gamepad = append(b.gamepad, buttonGamepad{g.button, button})
+	}
+
+	var err error
+	if b.hotkeys.frameStep, err = parse(cfg.Hotkeys.FrameStep); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.resume, err = parse(cfg.Hotkeys.Resume); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.halt, err = parse(cfg.Hotkeys.Halt); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.reset, err = parse(cfg.Hotkeys.Reset); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.saveState, err = parse(cfg.Hotkeys.SaveState); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.loadState, err = parse(cfg.Hotkeys.LoadState); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.prevSlot, err = parse(cfg.Hotkeys.PrevSlot); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.nextSlot, err = parse(cfg.Hotkeys.NextSlot); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.rewind, err = parse(cfg.Hotkeys.Rewind); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.fastForward, err = parse(cfg.Hotkeys.FastForward); err != nil {
+		return nil, err
+	}
+	if b.hotkeys.slowMotion, err = parse(cfg.Hotkeys.SlowMotion); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// buttons returns the Game Boy buttons currently held on the keyboard and
+// on this instance's gamepad.
+func (b *bindings) buttons() input.Button {
+	var buttons input.Button
+	for _, k := range b.keys {
+		if k.key.pressed() {
+			buttons |= k.button
+		}
+	}
+
+	if b.gamepadPlayer < 0 {
+		return buttons
+	}
+	b.gamepadIDs = ebiten.AppendGamepadIDs(b.gamepadIDs[:0])
+	if b.gamepadPlayer >= len(b.gamepadIDs) {
+		return buttons
+	}
+	id := b.gamepadIDs[b.gamepadPlayer]
+	if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+		return buttons
+	}
+	for _, g := range b.gamepad {
+		if ebiten.IsStandardGamepadButtonPressed(id, g.gamepad) {
+			buttons |= g.button
+		}
+	}
+	return buttons
+}
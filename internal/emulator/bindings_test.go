@@ -0,0 +1,61 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/USA-RedDragon/configulator"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+)
+
+func TestDuplicateParsedBindings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		want   error
+	}{
+		{"defaults", func(_ *config.Config) {}, nil},
+		{"arrow alias", func(cfg *config.Config) {
+			cfg.Keys.Down = "Down"
+			cfg.Hotkeys.Rewind = "ArrowDown"
+		}, config.ErrDuplicateKeyBinding},
+		{"digit alias", func(cfg *config.Config) {
+			cfg.Keys.A = "0"
+			cfg.Keys.B = "Digit0"
+		}, config.ErrDuplicateKeyBinding},
+		{"quote alias", func(cfg *config.Config) {
+			cfg.Keys.A = "Quote"
+			cfg.Hotkeys.Halt = "Apostrophe"
+		}, config.ErrDuplicateKeyBinding},
+		{"backquote alias", func(cfg *config.Config) {
+			cfg.Hotkeys.Reset = "GraveAccent"
+		}, config.ErrDuplicateKeyBinding},
+		{"gamepad case", func(cfg *config.Config) {
+			cfg.Gamepad.Start = "centerleft"
+		}, config.ErrDuplicateGamepadBinding},
+	}
+
+	defConfig, err := configulator.New[config.Config]().Default()
+	if err != nil {
+		t.Fatalf("failed to create default config: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defConfig
+			tt.modify(&cfg)
+			_, err := newBindings(&cfg)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("newBindings() unexpected error = %v", err)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("newBindings() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
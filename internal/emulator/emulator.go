@@ -1,35 +1,249 @@
 package emulator
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/USA-RedDragon/go-gb/internal/cartridge"
 	"github.com/USA-RedDragon/go-gb/internal/config"
-	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/gdbstub"
 	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/link"
+	"github.com/USA-RedDragon/go-gb/internal/printer"
+	"github.com/USA-RedDragon/go-gb/internal/rewind"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+	"github.com/USA-RedDragon/go-gb/internal/trace"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
 	ebiten "github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"golang.org/x/image/draw"
 )
 
 type Emulator struct {
 	config    *config.Config
-	cpu       *cpu.SM83
-	stopped   bool
+	gb        *gameboy.GameBoy
+	bindings  *bindings
+	capture   *serial.Capture // Serial output capture, if any
+	traceFile io.WriteCloser  // Instruction trace, if any
+	cable     *link.Cable     // Link cable to another emulator, if any
+	gdb       *gdbstub.Server // GDB stub, if enabled
+	slot      int             // Selected save state slot
+	stopping  atomic.Bool     // Set by Stop to end the game loop
 	frametime int
 	frame     []byte
+
+	slowMotion bool
+	frameDebt  float64 // Frames due to be emulated, carried between ticks
+
+	rewind       *rewind.Buffer // Rewind history, if enabled
+	snapshot     bytes.Buffer   // Scratch space for rewind snapshots
+	rewindFrames int            // Frames emulated since rewind was enabled
+
+	frameLimit int                     // Frames to emulate before exiting, or 0 for no limit
+	frames     int                     // Frames emulated so far
+	lastFrame  [gameboy.FrameSize]byte // Most recently emulated frame
+
+	watchHit string // The watchpoint hit that halted emulation, if any
 }
 
-func New(config *config.Config, cartridge *cartridge.Cartridge) *Emulator {
+// New creates an emulator for the ROM, which may be empty to run the boot ROM
+// at config.BIOS alone.
+func New(config *config.Config, rom []byte) (*Emulator, error) {
+	bindings, err := newBindings(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bindings: %w", err)
+	}
+	options, err := gameboyOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	gb, err := gameboy.New(rom, options)
+	if err != nil {
+		return nil, err
+	}
 	emu := &Emulator{
-		config: config,
-		cpu:    cpu.NewSM83(config, cartridge),
+		config:   config,
+		gb:       gb,
+		bindings: bindings,
+	}
+
+	for _, watch := range config.Watch {
+		fields := strings.Fields(watch)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w: %q", gameboy.ErrInvalidWatchpoint, watch)
+		}
+		w, err := gameboy.ParseWatchpoint(fields[0], fields[1], strings.Join(fields[2:], ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse watchpoint %q: %w", watch, err)
+		}
+		emu.gb.AddWatchpoint(w)
+	}
+
+	// Recording resets the Game Boy, so it has to start before playback
+	if config.RecordMovie != "" {
+		emu.gb.RecordMovie()
+	}
+	if config.PlayMovie != "" {
+		file, err := os.Open(config.PlayMovie)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open movie: %w", err)
+		}
+		defer file.Close()
+		if err := emu.gb.PlayMovie(file); err != nil {
+			return nil, fmt.Errorf("failed to play movie: %w", err)
+		}
+	}
+	if config.Trace != "" {
+		emu.traceFile, err = trace.Create(config.Trace)
+		if err != nil {
+			return nil, err
+		}
+		if err := emu.gb.StartTrace(emu.traceFile, config.TraceStart, config.TraceStop); err != nil {
+			emu.traceFile.Close()
+			return nil, err
+		}
+	}
+	if config.Profile != "" {
+		emu.gb.StartProfile()
+	}
+	if config.RewindSeconds > 0 {
+		emu.rewind = rewind.NewBuffer(config.RewindSeconds*framesPerSecond/config.RewindInterval, rewindKeyframeEvery)
+	}
+	if config.SerialOutput != "" {
+		emu.capture, err = serial.OpenCapture(config.SerialOutput)
+		if err != nil {
+			return nil, err
+		}
+		emu.gb.SetSerialPeer(emu.capture)
+	}
+	emu.cable, err = link.Open(config.LinkListen, config.LinkConnect)
+	if err != nil {
+		return nil, err
+	}
+	if emu.cable != nil {
+		emu.gb.SetSerialPeer(emu.cable)
+	}
+	if config.PrinterOutput != "" {
+		emu.gb.SetSerialPeer(printer.NewPrinter(config.PrinterOutput))
+	}
+	if config.GDB != "" {
+		emu.gdb, err = gdbstub.Listen(config.GDB, emu.gb)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return emu, nil
+}
+
+// gameboyOptions loads the boot ROM and symbols set in cfg, if any.
+func gameboyOptions(cfg *config.Config) (gameboy.Options, error) {
+	options := gameboy.Options{Trace: cfg.LogLevel == config.LogLevelDebug}
+	if cfg.BIOS != "" {
+		var err error
+		options.BootROM, err = os.ReadFile(cfg.BIOS)
+		if err != nil {
+			return options, fmt.Errorf("failed to load BIOS: %w", err)
+		}
+	}
+	if path := symbols.Find(cfg.ROM, cfg.Symbols); path != "" {
+		var err error
+		options.Symbols, err = os.ReadFile(path)
+		if err != nil {
+			return options, fmt.Errorf("failed to load symbols: %w", err)
+		}
+	}
+	return options, nil
+}
+
+// Close writes out the movie being recorded, the trace and the profile, and
+// closes the serial output, link cable and GDB stub, if any. It carries on
+// past errors, so one failure doesn't lose the other outputs, and returns
+// them all.
+func (e *Emulator) Close() error {
+	var errs []error
+	if e.gdb != nil {
+		if err := e.gdb.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close gdb stub: %w", err))
+		}
+		e.gdb = nil
+	}
+	if e.traceFile != nil {
+		if err := e.gb.StopTrace(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write trace: %w", err))
+		}
+		if err := e.traceFile.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close trace: %w", err))
+		}
+		e.traceFile = nil
 	}
-	return emu
+	if e.config.Profile != "" {
+		if err := e.writeProfile(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if e.cable != nil {
+		if err := e.cable.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close link cable: %w", err))
+		}
+		e.cable = nil
+	}
+	if e.capture != nil {
+		if err := e.capture.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close serial output: %w", err))
+		}
+		e.capture = nil
+	}
+	if e.gb.Recording() {
+		if err := e.writeMovie(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeMovie writes the movie started by New to the configured path.
+func (e *Emulator) writeMovie() error {
+	file, err := os.Create(e.config.RecordMovie)
+	if err != nil {
+		return fmt.Errorf("failed to create movie: %w", err)
+	}
+	if err := e.gb.StopRecording(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write movie: %w", err)
+	}
+	return nil
+}
+
+// SetFrameLimit makes the emulator exit once it has emulated the given number
+// of frames. A limit of 0 runs until the window is closed.
+func (e *Emulator) SetFrameLimit(frames int) {
+	e.frameLimit = frames
+}
+
+// GameBoy returns the emulated Game Boy.
+func (e *Emulator) GameBoy() *gameboy.GameBoy {
+	return e.gb
+}
+
+// LastFrame returns the most recently emulated frame.
+func (e *Emulator) LastFrame() [gameboy.FrameSize]byte {
+	return e.lastFrame
+}
+
+func (e *Emulator) reachedFrameLimit() bool {
+	return e.frameLimit > 0 && e.frames >= e.frameLimit
 }
 
 func (e *Emulator) upscale(render *image.RGBA) []byte {
@@ -45,7 +259,7 @@ func (e *Emulator) upscale(render *image.RGBA) []byte {
 	return upscaledImage.Pix
 }
 
-func (e *Emulator) convertToScreen(frame [23040]byte) []byte {
+func (e *Emulator) convertToScreen(frame [gameboy.FrameSize]byte) []byte {
 	originalRender := image.NewRGBA(image.Rect(0, 0, 160, 144))
 
 	// The input frame is expected to be in 2BPP format, where each pixel is represented by 2 bits.
@@ -89,81 +303,141 @@ func (e *Emulator) convertToScreen(frame [23040]byte) []byte {
 }
 
 func (e *Emulator) updateFrame() {
-	e.frame = e.convertToScreen(e.cpu.RunUntilFrame())
+	e.frame = e.convertToScreen(e.emulateFrame())
+}
+
+func (e *Emulator) updateInput() {
+	if e.gb.PlayingMovie() {
+		return
+	}
+	e.gb.SetButtons(gameboy.Button(e.bindings.buttons()))
 }
 
 func (e *Emulator) Update() error {
+	if e.stopping.Load() {
+		return ebiten.Termination
+	}
 	start := time.Now()
 
+	e.updateInput()
+
+	// A connected debugger decides when the Game Boy runs
+	if e.gdb != nil && e.gdb.Service() {
+		e.frame = e.convertToScreen(e.gb.Frame())
+		e.frametime = int(time.Since(start).Milliseconds())
+		return nil
+	}
+
+	if e.updateRewind() {
+		e.frametime = int(time.Since(start).Milliseconds())
+		return nil
+	}
+
 	// Frame stepping
-	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
-		if e.cpu.IsHalted() {
-			e.cpu.Resume()
+	if e.bindings.hotkeys.frameStep.justPressed() {
+		if e.gb.Halted() {
+			e.gb.Resume()
 		}
 		e.updateFrame()
-		e.cpu.Halt()
-	} else if inpututil.KeyPressDuration(ebiten.KeyF) > 30 {
-		if e.cpu.IsHalted() {
-			e.cpu.Resume()
+		e.gb.Halt()
+	} else if e.bindings.hotkeys.frameStep.pressDuration() > 30 {
+		if e.gb.Halted() {
+			e.gb.Resume()
 		}
 		e.updateFrame()
-		e.cpu.Halt()
+		e.gb.Halt()
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
-		e.cpu.Resume()
+	if e.bindings.hotkeys.resume.justPressed() {
+		e.gb.Resume()
+		e.watchHit = ""
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
-		e.cpu.Halt()
+	if e.bindings.hotkeys.halt.justPressed() {
+		e.gb.Halt()
 	}
 
-	if !e.cpu.IsHalted() {
-		e.updateFrame()
+	e.updateSpeed()
+
+	if !e.gb.Halted() {
+		e.runFrames()
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
-		halted := e.cpu.IsHalted()
-		e.cpu.Reset()
-		if halted {
-			e.cpu.Halt()
+	if e.bindings.hotkeys.reset.justPressed() {
+		if e.movieActive() {
+			// Movies replay input from power on, so a reset would desync them
+			slog.Warn("Not resetting while a movie is playing or recording")
+		} else {
+			halted := e.gb.Halted()
+			e.gb.Reset()
+			if halted {
+				e.gb.Halt()
+			}
 		}
 	}
 
+	e.updateStates()
+
 	e.frametime = int(time.Since(start).Milliseconds())
+	if e.reachedFrameLimit() {
+		return ebiten.Termination
+	}
 	return nil
 }
 
 func (e *Emulator) Draw(screen *ebiten.Image) {
-	if e.stopped {
+	if e.stopping.Load() {
 		return
 	}
 	screen.WritePixels(e.frame)
+	pc := e.gb.Registers().PC
 	ebitenutil.DebugPrint(
 		screen,
 		fmt.Sprintf(
-			"FPS: %0.2f\nFrame Time: %dms\nTPS: %0.2f\nPC: 0x%04X\n%s",
+			"FPS: %0.2f\nFrame Time: %dms\nTPS: %0.2f\nPC: 0x%04X %s\nSlot: %d\n%s%s",
 			1000.0/float64(e.frametime),
 			e.frametime,
 			ebiten.ActualTPS(),
-			e.cpu.GetPC(),
+			pc,
+			e.gb.Symbolize(pc),
+			e.slot,
 			fmt.Sprintf("Interrupts:\n\tJoy: %t, Serial: %t, Timer: %t, LCD: %t, VBlank: %t\n",
-				e.cpu.GetInterruptEnableFlag(impls.JoypadInterrupt),
-				e.cpu.GetInterruptEnableFlag(impls.SerialInterrupt),
-				e.cpu.GetInterruptEnableFlag(impls.TimerInterrupt),
-				e.cpu.GetInterruptEnableFlag(impls.LCDInterrupt),
-				e.cpu.GetInterruptEnableFlag(impls.VBlankInterrupt),
+				e.interruptEnabled(impls.JoypadInterrupt),
+				e.interruptEnabled(impls.SerialInterrupt),
+				e.interruptEnabled(impls.TimerInterrupt),
+				e.interruptEnabled(impls.LCDInterrupt),
+				e.interruptEnabled(impls.VBlankInterrupt),
 			),
+			e.watchHit,
 		),
 	)
 }
 
+// interruptEnabled reports whether the interrupt is set in IE.
+func (e *Emulator) interruptEnabled(interrupt impls.Interrupt) bool {
+	return e.gb.Peek(0xFFFF)&byte(interrupt) != 0
+}
+
 func (e *Emulator) Layout(_, _ int) (int, int) {
 	return int(e.config.Scale * 160), int(e.config.Scale * 144)
 }
 
+// Stop makes the game loop return ebiten.Termination at its next update, so
+// that the caller of ebiten.RunGame can Close the emulator. It's safe to call
+// from any goroutine, such as a signal handler.
 func (e *Emulator) Stop() {
-	e.stopped = true
-	e.cpu.Halt()
-	os.Exit(0)
+	e.stopping.Store(true)
+}
+
+// writeProfile writes the profile started by New to the configured path.
+func (e *Emulator) writeProfile() error {
+	file, err := os.Create(e.config.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+	if err := e.gb.StopProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
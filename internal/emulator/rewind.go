@@ -0,0 +1,61 @@
+package emulator
+
+import (
+	"bytes"
+	"log/slog"
+)
+
+const (
+	// framesPerSecond is the Game Boy's frame rate, rounded.
+	framesPerSecond = 60
+	// rewindKeyframeEvery is the number of rewind snapshots per keyframe.
+	rewindKeyframeEvery = 60
+)
+
+// updateRewind plays the game backwards while the rewind hotkey is held. It
+// reports whether the game was rewound instead of run.
+func (e *Emulator) updateRewind() bool {
+	if !e.rewindEnabled() || !e.bindings.hotkeys.rewind.pressed() {
+		return false
+	}
+
+	snapshot, ok, err := e.rewind.Pop()
+	if err != nil {
+		slog.Error("Failed to rewind", "error", err)
+		return true
+	}
+	if !ok {
+		// Out of history, hold the oldest frame
+		return true
+	}
+	if err := e.gb.LoadState(bytes.NewReader(snapshot)); err != nil {
+		slog.Error("Failed to rewind", "error", err)
+		return true
+	}
+	e.frame = e.convertToScreen(e.gb.Frame())
+	return true
+}
+
+// snapshotForRewind records the machine ahead of the frame about to be
+// emulated, every RewindInterval frames.
+func (e *Emulator) snapshotForRewind() {
+	if !e.rewindEnabled() {
+		return
+	}
+	e.rewindFrames++
+	if e.rewindFrames%e.config.RewindInterval != 0 {
+		return
+	}
+	e.snapshot.Reset()
+	if err := e.gb.SaveState(&e.snapshot); err != nil {
+		slog.Error("Failed to snapshot for rewind", "error", err)
+		return
+	}
+	if err := e.rewind.Push(e.snapshot.Bytes()); err != nil {
+		slog.Error("Failed to snapshot for rewind", "error", err)
+	}
+}
+
+func (e *Emulator) rewindEnabled() bool {
+	return e.rewind != nil && !e.movieActive()
+}
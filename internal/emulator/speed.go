@@ -0,0 +1,77 @@
+package emulator
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+	ebiten "github.com/hajimehoshi/ebiten/v2"
+)
+
+// fastForwardShare is the share of each tick fast-forwarding may spend
+// emulating, leaving the rest for drawing.
+const fastForwardShare = 0.75
+
+// updateSpeed handles the slow motion hotkey.
+func (e *Emulator) updateSpeed() {
+	if e.bindings.hotkeys.slowMotion.justPressed() {
+		e.slowMotion = !e.slowMotion
+		slog.Info("Toggled slow motion", "enabled", e.slowMotion)
+	}
+}
+
+// runFrames emulates as many frames as are due this tick and shows the last
+// of them. Pacing happens per frame rather than per cycle: ebiten calls
+// Update at a steady rate, and each call runs the frames that fit in it at
+// the current speed.
+func (e *Emulator) runFrames() {
+	tps := ebiten.TPS()
+	if tps <= 0 {
+		tps = ebiten.DefaultTPS
+	}
+
+	var frame [gameboy.FrameSize]byte
+	ran := false
+	if e.config.Uncapped || e.bindings.hotkeys.fastForward.pressed() {
+		deadline := time.Now().Add(time.Duration(fastForwardShare * float64(time.Second) / float64(tps)))
+		for (!ran || time.Now().Before(deadline)) && !e.reachedFrameLimit() && !e.gb.Halted() {
+			frame = e.emulateFrame()
+			ran = true
+		}
+		e.frameDebt = 0
+	} else {
+		speed := e.config.Speed
+		if e.slowMotion {
+			speed *= e.config.SlowMotion
+		}
+		perTick := speed * consts.FrameRate / float64(tps)
+		// Don't try to catch up on more than a frame after a stall
+		e.frameDebt = min(e.frameDebt+perTick, perTick+1)
+		for e.frameDebt >= 1 && !e.reachedFrameLimit() && !e.gb.Halted() {
+			e.frameDebt--
+			frame = e.emulateFrame()
+			ran = true
+		}
+	}
+
+	if ran {
+		e.frame = e.convertToScreen(frame)
+	}
+}
+
+// emulateFrame runs the machine for a single frame, halting it if a
+// watchpoint is hit.
+func (e *Emulator) emulateFrame() [gameboy.FrameSize]byte {
+	e.snapshotForRewind()
+	e.gb.StepFrame()
+	if hit, ok := e.gb.WatchHit(); ok {
+		e.gb.Halt()
+		e.watchHit = fmt.Sprintf("Watchpoint: %s\n", hit)
+		slog.Info("Halted at watchpoint", "hit", hit)
+	}
+	e.lastFrame = e.gb.Frame()
+	e.frames++
+	return e.lastFrame
+}
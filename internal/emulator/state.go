@@ -0,0 +1,89 @@
+package emulator
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stateSlots is the number of save state slots selectable with the hotkeys.
+const stateSlots = 10
+
+// statePath returns the file backing a save state slot, named after the ROM.
+func (e *Emulator) statePath(slot int) string {
+	name := "go-gb"
+	dir := e.config.StateDir
+	if e.config.ROM != "" {
+		name = strings.TrimSuffix(filepath.Base(e.config.ROM), filepath.Ext(e.config.ROM))
+		if dir == "" {
+			dir = filepath.Dir(e.config.ROM)
+		}
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.ss%d", name, slot))
+}
+
+func (e *Emulator) saveState() error {
+	path := e.statePath(e.slot)
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create save state: %w", err)
+	}
+	if err := e.gb.SaveState(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write save state: %w", err)
+	}
+	slog.Info("Saved state", "slot", e.slot, "path", path)
+	return nil
+}
+
+func (e *Emulator) loadState() error {
+	path := e.statePath(e.slot)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open save state: %w", err)
+	}
+	if err := e.gb.LoadState(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close save state: %w", err)
+	}
+	slog.Info("Loaded state", "slot", e.slot, "path", path)
+	return nil
+}
+
+// updateStates handles the save state hotkeys.
+func (e *Emulator) updateStates() {
+	if e.bindings.hotkeys.prevSlot.justPressed() {
+		e.slot = (e.slot + stateSlots - 1) % stateSlots
+		slog.Info("Selected save state slot", "slot", e.slot)
+	}
+	if e.bindings.hotkeys.nextSlot.justPressed() {
+		e.slot = (e.slot + 1) % stateSlots
+		slog.Info("Selected save state slot", "slot", e.slot)
+	}
+	if e.bindings.hotkeys.saveState.justPressed() {
+		if err := e.saveState(); err != nil {
+			slog.Error("Failed to save state", "slot", e.slot, "error", err)
+		}
+	}
+	if e.bindings.hotkeys.loadState.justPressed() {
+		if e.movieActive() {
+			slog.Warn("Not loading a state while a movie is playing or recording", "slot", e.slot)
+		} else if err := e.loadState(); err != nil {
+			slog.Error("Failed to load state", "slot", e.slot, "error", err)
+		}
+	}
+}
+
+// movieActive reports whether a movie is being played or recorded, which
+// jumping to another machine state would desync.
+func (e *Emulator) movieActive() bool {
+	return e.gb.Recording() || e.gb.PlayingMovie()
+}
@@ -0,0 +1,298 @@
+package gdbstub
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+)
+
+// targetXML describes the SM83's registers to the client, in the order g
+// sends them.
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <architecture>sm83</architecture>
+  <feature name="org.go-gb.sm83">
+    <reg name="a" bitsize="8" type="uint8" regnum="0"/>
+    <reg name="f" bitsize="8" type="uint8"/>
+    <reg name="b" bitsize="8" type="uint8"/>
+    <reg name="c" bitsize="8" type="uint8"/>
+    <reg name="d" bitsize="8" type="uint8"/>
+    <reg name="e" bitsize="8" type="uint8"/>
+    <reg name="h" bitsize="8" type="uint8"/>
+    <reg name="l" bitsize="8" type="uint8"/>
+    <reg name="sp" bitsize="16" type="data_ptr"/>
+    <reg name="pc" bitsize="16" type="code_ptr"/>
+  </feature>
+</target>
+`
+
+// Replies
+const (
+	replyOK          = "OK"
+	replyError       = "E01"
+	replyUnsupported = ""
+	replyTrap        = "S05" // Stopped by a step or breakpoint
+)
+
+// maxPacket is the largest packet the stub accepts, in bytes.
+const maxPacket = 4096
+
+// command handles a packet from the client.
+func (s *Server) command(packet string) {
+	if packet == "" {
+		s.send(replyUnsupported)
+		return
+	}
+	args := packet[1:]
+	switch packet[0] {
+	case '?':
+		s.send(replyTrap)
+	case 'g':
+		s.send(encodeRegisters(s.target.Registers()))
+	case 'G':
+		s.send(s.writeRegisters(args))
+	case 'p':
+		s.send(s.readRegister(args))
+	case 'P':
+		s.send(s.writeRegister(args))
+	case 'm':
+		s.send(s.readMemory(args))
+	case 'M':
+		s.send(s.writeMemory(args))
+	case 'c':
+		s.resume(args)
+		s.running = true
+	case 's':
+		s.resume(args)
+		s.target.Step()
+		s.send(replyTrap)
+	case 'Z', 'z':
+		s.send(s.breakpoint(packet[0] == 'Z', args))
+	case 'H', 'T':
+		// There is only one thread
+		s.send(replyOK)
+	case 'D':
+		s.send(replyOK)
+		s.running = false
+		s.breakpoints = make(map[uint16]bool)
+		s.writeMu.Lock()
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		s.writeMu.Unlock()
+	case 'k':
+		s.writeMu.Lock()
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		s.writeMu.Unlock()
+	case 'q', 'Q':
+		s.send(s.query(packet))
+	default:
+		s.send(replyUnsupported)
+	}
+}
+
+func (s *Server) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return fmt.Sprintf("PacketSize=%x;qXfer:features:read+;QStartNoAckMode+", maxPacket)
+	case packet == "QStartNoAckMode":
+		s.noAck.Store(true)
+		return replyOK
+	case strings.HasPrefix(packet, "qXfer:features:read:target.xml:"):
+		return readXfer(targetXML, strings.TrimPrefix(packet, "qXfer:features:read:target.xml:"))
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	}
+	return replyUnsupported
+}
+
+// readXfer returns the part of document asked for as offset,length.
+func readXfer(document, args string) string {
+	offsetText, lengthText, _ := strings.Cut(args, ",")
+	offset, err1 := strconv.ParseUint(offsetText, 16, 32)
+	length, err2 := strconv.ParseUint(lengthText, 16, 32)
+	if err1 != nil || err2 != nil {
+		return replyError
+	}
+	if offset >= uint64(len(document)) {
+		return "l"
+	}
+	rest := document[offset:]
+	if length < uint64(len(rest)) {
+		return "m" + rest[:length]
+	}
+	return "l" + rest
+}
+
+// resume moves the PC to the address a c or s packet gives, if any, and
+// steps over any breakpoint there.
+func (s *Server) resume(args string) {
+	if addr, err := strconv.ParseUint(args, 16, 16); err == nil {
+		r := s.target.Registers()
+		r.PC = uint16(addr)
+		s.target.SetRegisters(r)
+	}
+	s.stepOver = true
+}
+
+// register points at one of the registers, which is 8-bit if byte is set.
+type register struct {
+	word *uint16
+	byte *byte
+}
+
+// registers returns the registers in the order of targetXML.
+func registers(r *gameboy.Registers) []register {
+	return []register{
+		{byte: &r.A}, {byte: &r.F}, {byte: &r.B}, {byte: &r.C},
+		{byte: &r.D}, {byte: &r.E}, {byte: &r.H}, {byte: &r.L},
+		{word: &r.SP}, {word: &r.PC},
+	}
+}
+
+// encodeRegisters encodes registers in target order, 16-bit ones little
+// endian.
+func encodeRegisters(r gameboy.Registers) string {
+	var out strings.Builder
+	for _, reg := range registers(&r) {
+		out.WriteString(reg.encode())
+	}
+	return out.String()
+}
+
+func (r register) encode() string {
+	if r.byte != nil {
+		return fmt.Sprintf("%02x", *r.byte)
+	}
+	return fmt.Sprintf("%02x%02x", byte(*r.word), byte(*r.word>>8))
+}
+
+// decode sets the register from the start of data, returning the rest.
+func (r register) decode(data []byte) ([]byte, bool) {
+	if r.byte != nil {
+		if len(data) < 1 {
+			return nil, false
+		}
+		*r.byte = data[0]
+		return data[1:], true
+	}
+	if len(data) < 2 {
+		return nil, false
+	}
+	*r.word = uint16(data[1])<<8 | uint16(data[0])
+	return data[2:], true
+}
+
+func (s *Server) writeRegisters(args string) string {
+	data, err := hex.DecodeString(args)
+	if err != nil {
+		return replyError
+	}
+	r := s.target.Registers()
+	for _, reg := range registers(&r) {
+		var ok bool
+		if data, ok = reg.decode(data); !ok {
+			return replyError
+		}
+	}
+	r.F &= 0xF0
+	s.target.SetRegisters(r)
+	return replyOK
+}
+
+func (s *Server) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	r := s.target.Registers()
+	regs := registers(&r)
+	if err != nil || n >= uint64(len(regs)) {
+		return replyError
+	}
+	return regs[n].encode()
+}
+
+func (s *Server) writeRegister(args string) string {
+	nText, valueText, _ := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(nText, 16, 8)
+	data, hexErr := hex.DecodeString(valueText)
+	r := s.target.Registers()
+	regs := registers(&r)
+	if err != nil || hexErr != nil || n >= uint64(len(regs)) {
+		return replyError
+	}
+	if _, ok := regs[n].decode(data); !ok {
+		return replyError
+	}
+	r.F &= 0xF0
+	s.target.SetRegisters(r)
+	return replyOK
+}
+
+// parseRange parses addr,length.
+func parseRange(args string) (uint16, int, bool) {
+	addrText, lengthText, _ := strings.Cut(args, ",")
+	addr, err1 := strconv.ParseUint(addrText, 16, 16)
+	length, err2 := strconv.ParseUint(lengthText, 16, 16)
+	if err1 != nil || err2 != nil || length > maxPacket/2 {
+		return 0, 0, false
+	}
+	return uint16(addr), int(length), true
+}
+
+func (s *Server) readMemory(args string) string {
+	addr, length, ok := parseRange(args)
+	if !ok {
+		return replyError
+	}
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = s.target.Peek(addr + uint16(i))
+	}
+	return hex.EncodeToString(data)
+}
+
+func (s *Server) writeMemory(args string) string {
+	rangeText, dataText, _ := strings.Cut(args, ":")
+	addr, length, ok := parseRange(rangeText)
+	data, err := hex.DecodeString(dataText)
+	if !ok || err != nil || len(data) != length {
+		return replyError
+	}
+	for i, b := range data {
+		if err := s.target.Poke(addr+uint16(i), b); err != nil {
+			return replyError
+		}
+	}
+	return replyOK
+}
+
+// breakpoint sets or clears a breakpoint from a Z or z packet. Software and
+// hardware breakpoints are both checked before each instruction, without
+// patching memory.
+func (s *Server) breakpoint(set bool, args string) string {
+	fields := strings.Split(args, ",")
+	if len(fields) < 2 || (fields[0] != "0" && fields[0] != "1") {
+		return replyUnsupported
+	}
+	addr, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return replyError
+	}
+	if set {
+		s.breakpoints[uint16(addr)] = true
+	} else {
+		delete(s.breakpoints, uint16(addr))
+	}
+	return replyOK
+}
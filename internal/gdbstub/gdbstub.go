@@ -0,0 +1,246 @@
+package gdbstub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+)
+
+// Target is the Game Boy the stub debugs.
+type Target interface {
+	Registers() gameboy.Registers
+	SetRegisters(registers gameboy.Registers)
+	Peek(addr uint16) byte
+	Poke(addr uint16, data byte) error
+	Step() int
+}
+
+type eventKind int
+
+const (
+	eventAttach    eventKind = iota // A client connected
+	eventPacket                     // The client sent a packet
+	eventInterrupt                  // The client pressed control-C
+	eventDetach                     // The client disconnected
+)
+
+type event struct {
+	kind   eventKind
+	conn   net.Conn
+	packet string
+}
+
+// Server is a GDB remote serial protocol stub for the SM83. A gdb-compatible
+// client connects over TCP, one at a time.
+//
+// The network is handled in the background, but the target is only touched
+// by Service, so the emulator can keep driving the Game Boy from its own
+// goroutine.
+type Server struct {
+	listener net.Listener
+	target   Target
+	events   chan event
+
+	writeMu sync.Mutex // Serializes writes to conn from Service and its reader
+	conn    net.Conn   // The connected client, if any
+	noAck   atomic.Bool
+
+	running     bool // Set while the client has continued the target
+	stepOver    bool // Set to run the instruction at a breakpoint continued from
+	breakpoints map[uint16]bool
+}
+
+// Listen starts a stub for target listening on addr, such as :1234.
+func Listen(addr string, target Target) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gdb: %w", err)
+	}
+	s := &Server{
+		listener:    listener,
+		target:      target,
+		events:      make(chan event, 64),
+		breakpoints: make(map[uint16]bool),
+	}
+	slog.Info("Waiting for gdb to connect", "address", listener.Addr().String())
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the stub is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops listening and disconnects the client, if any.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	return err
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.events <- event{kind: eventAttach, conn: conn}
+		s.read(conn)
+		s.events <- event{kind: eventDetach, conn: conn}
+	}
+}
+
+// read turns what the client sends into events until it disconnects.
+func (s *Server) read(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		switch b {
+		case 0x03:
+			s.events <- event{kind: eventInterrupt, conn: conn}
+		case '$':
+			packet, err := readPacket(r)
+			switch {
+			case errors.Is(err, errChecksum):
+				s.ack(conn, '-')
+			case err != nil:
+				return
+			default:
+				s.ack(conn, '+')
+				s.events <- event{kind: eventPacket, conn: conn, packet: packet}
+			}
+		}
+		// Acknowledgements from the client need no action
+	}
+}
+
+var errChecksum = errors.New("bad packet checksum")
+
+// readPacket reads the rest of a packet after its $.
+func readPacket(r *bufio.Reader) (string, error) {
+	data, err := r.ReadString('#')
+	if err != nil {
+		return "", err
+	}
+	data = data[:len(data)-1]
+	var sum [2]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return "", err
+	}
+	var want byte
+	if _, err := fmt.Sscanf(string(sum[:]), "%02x", &want); err != nil || checksum(data) != want {
+		return "", errChecksum
+	}
+	return data, nil
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for i := range len(data) {
+		sum += data[i]
+	}
+	return sum
+}
+
+func (s *Server) ack(conn net.Conn, b byte) {
+	if s.noAck.Load() {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, _ = conn.Write([]byte{b})
+}
+
+// send writes a packet to the client.
+func (s *Server) send(data string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.conn == nil {
+		return
+	}
+	if _, err := fmt.Fprintf(s.conn, "$%s#%02x", data, checksum(data)); err != nil {
+		slog.Warn("Failed to write to gdb", "error", err)
+	}
+}
+
+// Service handles what the client has sent since the last call and, if the
+// client has continued the target, runs it for up to a frame. It reports
+// whether a client is connected, in which case the client rather than the
+// caller decides when the target runs.
+func (s *Server) Service() bool {
+	for drained := false; !drained; {
+		select {
+		case e := <-s.events:
+			s.handle(e)
+		default:
+			drained = true
+		}
+	}
+	if s.conn == nil {
+		return false
+	}
+	if s.running {
+		s.run(consts.CyclesPerFrame)
+	}
+	return true
+}
+
+func (s *Server) handle(e event) {
+	switch e.kind {
+	case eventAttach:
+		s.writeMu.Lock()
+		s.conn = e.conn
+		s.writeMu.Unlock()
+		s.noAck.Store(false)
+		s.running = false
+		slog.Info("gdb connected", "address", e.conn.RemoteAddr().String())
+	case eventDetach:
+		s.writeMu.Lock()
+		if s.conn == e.conn {
+			s.conn = nil
+		}
+		s.writeMu.Unlock()
+		e.conn.Close()
+		s.running = false
+		s.breakpoints = make(map[uint16]bool)
+		slog.Info("gdb disconnected")
+	case eventInterrupt:
+		if s.running {
+			s.running = false
+			s.send("S02")
+		}
+	case eventPacket:
+		if e.conn == s.conn {
+			s.command(e.packet)
+		}
+	}
+}
+
+// run runs the target until a breakpoint or for the given M-cycles.
+func (s *Server) run(cycles int) {
+	for ran := 0; ran < cycles; {
+		if s.breakpoints[s.target.Registers().PC] && !s.stepOver {
+			s.running = false
+			s.send("S05")
+			return
+		}
+		s.stepOver = false
+		ran += s.target.Step()
+	}
+}
@@ -0,0 +1,132 @@
+package gdbstub_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/gdbstub"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+)
+
+// counterROM returns a ROM that turns the LCD on and increments 0xC000
+// forever, with the increment at 0x0157.
+func counterROM() []byte {
+	rom := make([]byte, 2*16384)
+	copy(rom[0x100:], []byte{0x00, 0xC3, 0x50, 0x01}) // NOP; JP 0x0150
+	copy(rom[0x150:], []byte{
+		0x3E, 0x91, // LD A,0x91
+		0xE0, 0x40, // LDH (0x40),A
+		0x21, 0x00, 0xC0, // LD HL,0xC000
+		0x34,       // INC (HL)
+		0x18, 0xFD, // JR -3
+	})
+	return rom
+}
+
+// client speaks the remote serial protocol to a stub.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *client) exchange(t *testing.T, packet string) string {
+	t.Helper()
+
+	var sum byte
+	for i := range len(packet) {
+		sum += packet[i]
+	}
+	if _, err := fmt.Fprintf(c.conn, "$%s#%02x", packet, sum); err != nil {
+		t.Fatalf("failed to send %q: %v", packet, err)
+	}
+	if ack, err := c.r.ReadByte(); err != nil || ack != '+' {
+		t.Fatalf("got ack %q, %v for %q", ack, err, packet)
+	}
+	if _, err := c.r.ReadString('$'); err != nil {
+		t.Fatalf("failed to read reply to %q: %v", packet, err)
+	}
+	reply, err := c.r.ReadString('#')
+	if err != nil {
+		t.Fatalf("failed to read reply to %q: %v", packet, err)
+	}
+	if _, err := io.ReadFull(c.r, make([]byte, 2)); err != nil {
+		t.Fatalf("failed to read checksum of reply to %q: %v", packet, err)
+	}
+	if _, err := c.conn.Write([]byte{'+'}); err != nil {
+		t.Fatalf("failed to acknowledge reply to %q: %v", packet, err)
+	}
+	return strings.TrimSuffix(reply, "#")
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	server, err := gdbstub.Listen("127.0.0.1:0", gb)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer server.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				server.Service()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	c := &client{conn: conn, r: bufio.NewReader(conn)}
+
+	steps := []struct {
+		packet string
+		want   string
+	}{
+		{"qSupported:multiprocess+", "PacketSize=1000;qXfer:features:read+;QStartNoAckMode+"},
+		{"qXfer:features:read:target.xml:0,5", "m<?xml"},
+		{"?", "S05"},
+		{"g", "01b0001300d8014dfeff0001"},
+		{"m150,3", "3e91e0"},
+		{"Mc000,2:2a2b", "OK"},
+		{"mc000,2", "2a2b"},
+		{"Z0,157,1", "OK"},
+		{"c", "S05"},
+		{"p9", "5701"},
+		{"s", "S05"},
+		{"p9", "5801"},
+		{"c", "S05"},
+		{"p9", "5701"},
+		{"z0,157,1", "OK"},
+		{"P0=42", "OK"},
+		{"p0", "42"},
+		{"Z2,c000,1", ""},
+		{"vMustReplyEmpty", ""},
+	}
+	for _, step := range steps {
+		if got := c.exchange(t, step.packet); got != step.want {
+			t.Errorf("%s got %q, want %q", step.packet, got, step.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ const (
 	VBlankInterrupt Interrupt = 1 << 0
 )
 
-type CPU interface {
+// Interrupts is how devices request interrupts.
+type Interrupts interface {
 	SetInterruptFlag(flag Interrupt, val bool)
 }
@@ -0,0 +1,19 @@
+package impls
+
+// SerialPeer is the device at the other end of the link cable.
+type SerialPeer interface {
+	// Exchange is called when this side, driving the clock, has shifted out a
+	// whole byte. It returns the byte shifted in from the peer.
+	Exchange(out byte) byte
+	// Poll is called while this side waits for the peer to drive the clock.
+	// Once the peer has clocked a whole byte it returns the byte shifted in
+	// and true, having taken out as the byte shifted out.
+	Poll(out byte) (byte, bool)
+}
+
+// SerialClock is implemented by serial peers that need to see every M-cycle,
+// such as a link to another emulator running in lockstep.
+type SerialClock interface {
+	// Tick is called once per M-cycle with the current contents of SB.
+	Tick(sb byte)
+}
@@ -1,15 +1,143 @@
 package input
 
+import "github.com/USA-RedDragon/go-gb/internal/impls"
+
+// Button is a bitmask of joypad buttons. The low nibble holds the d-pad and
+// the high nibble the action buttons, in the same bit order as JOYP.
+type Button uint8
+
+const (
+	ButtonRight Button = 1 << iota
+	ButtonLeft
+	ButtonUp
+	ButtonDown
+	ButtonA
+	ButtonB
+	ButtonSelect
+	ButtonStart
+)
+
+const (
+	selectDPad    byte = 1 << 4 // P14, 0 = d-pad selected
+	selectButtons byte = 1 << 5 // P15, 0 = buttons selected
+	selectMask         = selectDPad | selectButtons
+)
+
 type Input struct {
-	JOYP byte // Joypad register
+	selectLines byte   // P14/P15 as last written by the CPU
+	buttons     Button // Currently pressed buttons, 1 = pressed
+	interrupts  impls.Interrupts
+
+	recording *Movie // Movie receiving every frame's buttons, if any
+	playing   *Movie // Movie supplying every frame's buttons, if any
+	playFrame int    // Next frame of the playing movie
 }
 
-func NewInput() *Input {
-	input := &Input{}
+func NewInput(interrupts impls.Interrupts) *Input {
+	input := &Input{
+		interrupts: interrupts,
+	}
 	input.Reset()
 	return input
 }
 
 func (s *Input) Reset() {
-	s.JOYP = 0xFF
+	s.selectLines = selectMask
+	s.buttons = 0
+}
+
+// JOYP returns the current value of the joypad register.
+func (s *Input) JOYP() byte {
+	// Inputs are active-low, and both rows are pulled high unless selected.
+	lines := byte(0x0F)
+	if s.selectLines&selectDPad == 0 {
+		lines &^= byte(s.buttons) & 0x0F
+	}
+	if s.selectLines&selectButtons == 0 {
+		lines &^= byte(s.buttons>>4) & 0x0F
+	}
+	return 0xC0 | s.selectLines | lines
+}
+
+func (s *Input) Read8(_ uint16) uint8 {
+	return s.JOYP()
+}
+
+func (s *Input) Write8(_ uint16, data uint8) {
+	s.update(func() {
+		s.selectLines = data & selectMask
+	})
+}
+
+// Buttons returns the set of currently pressed buttons.
+func (s *Input) Buttons() Button {
+	return s.buttons
+}
+
+// SetButtons replaces the set of currently pressed buttons.
+func (s *Input) SetButtons(buttons Button) {
+	s.update(func() {
+		s.buttons = buttons
+	})
+}
+
+// SetButton presses or releases a single button.
+func (s *Input) SetButton(button Button, pressed bool) {
+	s.update(func() {
+		if pressed {
+			s.buttons |= button
+		} else {
+			s.buttons &^= button
+		}
+	})
+}
+
+// update applies a change to the select lines or buttons and raises the
+// joypad interrupt if any input line went from high to low.
+func (s *Input) update(change func()) {
+	before := s.JOYP() & 0x0F
+	change()
+	after := s.JOYP() & 0x0F
+	if before&^after != 0 {
+		s.interrupts.SetInterruptFlag(impls.JoypadInterrupt, true)
+	}
+}
+
+// Record appends the buttons seen by every following frame to movie.
+func (s *Input) Record(movie *Movie) {
+	s.recording = movie
+}
+
+// StopRecording stops appending frames to the movie being recorded.
+func (s *Input) StopRecording() {
+	s.recording = nil
+}
+
+// Play replaces the live buttons with the frames of movie until it ends.
+func (s *Input) Play(movie *Movie) {
+	s.playing = movie
+	s.playFrame = 0
+}
+
+// Playing reports whether a movie is still supplying the buttons.
+func (s *Input) Playing() bool {
+	return s.playing != nil
+}
+
+// NextFrame latches the buttons for the frame about to be emulated. It must
+// be called exactly once at the start of every frame for movies to stay in
+// sync.
+func (s *Input) NextFrame() {
+	if s.playing != nil {
+		if s.playFrame < len(s.playing.Frames) {
+			s.SetButtons(s.playing.Frames[s.playFrame])
+			s.playFrame++
+		} else {
+			s.playing = nil
+			s.SetButtons(0)
+		}
+	}
+	if s.recording != nil {
+		s.recording.Frames = append(s.recording.Frames, s.buttons)
+	}
 }
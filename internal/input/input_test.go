@@ -0,0 +1,71 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	"github.com/USA-RedDragon/go-gb/internal/interrupts"
+)
+
+func TestJOYP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		selects byte // Written to JOYP
+		buttons input.Button
+		want    byte
+	}{
+		{"nothing selected", 0x30, input.ButtonRight | input.ButtonA, 0xFF},
+		{"d-pad", 0x20, input.ButtonRight | input.ButtonA, 0xEE},
+		{"d-pad down", 0x20, input.ButtonDown | input.ButtonStart, 0xE7},
+		{"buttons", 0x10, input.ButtonRight | input.ButtonA, 0xDE},
+		{"buttons start", 0x10, input.ButtonDown | input.ButtonStart, 0xD7},
+		{"both", 0x00, input.ButtonRight | input.ButtonB, 0xCC},
+		{"both on the same line", 0x00, input.ButtonRight | input.ButtonA, 0xCE},
+		{"low bits ignored", 0x2F, input.ButtonLeft, 0xED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			joypad := input.NewInput(&interrupts.Controller{})
+			joypad.Write8(0xFF00, tt.selects)
+			joypad.SetButtons(tt.buttons)
+			if got := joypad.Read8(0xFF00); got != tt.want {
+				t.Errorf("JOYP = 0x%02X, want 0x%02X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoypadInterrupt(t *testing.T) {
+	t.Parallel()
+
+	irq := &interrupts.Controller{}
+	joypad := input.NewInput(irq)
+	steps := []struct {
+		name string
+		run  func()
+		want bool
+	}{
+		{"select d-pad", func() { joypad.Write8(0xFF00, 0x20) }, false},
+		{"press unselected button", func() { joypad.SetButton(input.ButtonA, true) }, false},
+		{"press selected direction", func() { joypad.SetButton(input.ButtonRight, true) }, true},
+		{"press held direction", func() { joypad.SetButton(input.ButtonRight, true) }, false},
+		{"press direction on another line", func() { joypad.SetButton(input.ButtonLeft, true) }, true},
+		{"release", func() { joypad.SetButtons(0) }, false},
+		{"press same line as unselected button", func() { joypad.SetButtons(input.ButtonA | input.ButtonRight) }, true},
+		{"select buttons with one held", func() { joypad.Write8(0xFF00, 0x10) }, false},
+		{"deselect", func() { joypad.Write8(0xFF00, 0x30) }, false},
+		{"select line with a button held", func() { joypad.Write8(0xFF00, 0x10) }, true},
+	}
+	for _, step := range steps {
+		irq.SetInterruptFlag(impls.JoypadInterrupt, false)
+		step.run()
+		if got := irq.GetInterruptFlag(impls.JoypadInterrupt); got != step.want {
+			t.Errorf("%s: joypad interrupt requested = %t, want %t", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	movieVersion = 1
+	// maxMovieBlob is the largest start state or frame list read from a
+	// movie, over a week of frames, so a corrupt length can't allocate
+	// gigabytes.
+	maxMovieBlob = 64 << 20
+)
+
+//nolint:gochecknoglobals
+var movieMagic = []byte("GBMV")
+
+var (
+	ErrInvalidMovie            = errors.New("not a go-gb movie file")
+	ErrUnsupportedMovieVersion = errors.New("unsupported movie version")
+	ErrMovieTooLarge           = errors.New("movie is too large")
+)
+
+// MovieHeader identifies the machine a movie was recorded on. Playback is only
+// bit-exact when it starts from the same ROM, model, boot ROM and state.
+type MovieHeader struct {
+	ROMHash     [sha256.Size]byte
+	Model       string
+	BootROMHash [sha256.Size]byte // All zeroes when recorded without a boot ROM
+	StartState  []byte            // Save state the movie starts from, empty for power-on
+}
+
+// Movie is a recording of the joypad state for every emulated frame.
+type Movie struct {
+	Header MovieHeader
+	Frames []Button
+}
+
+// ReadMovie decodes a movie previously written with Movie.Write.
+func ReadMovie(r io.Reader) (*Movie, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(movieMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("failed to read movie magic: %w", err)
+	}
+	if !bytes.Equal(magic, movieMagic) {
+		return nil, ErrInvalidMovie
+	}
+
+	var version uint8
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("failed to read movie version: %w", err)
+	}
+	if version != movieVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedMovieVersion, version)
+	}
+
+	movie := &Movie{}
+	if _, err := io.ReadFull(br, movie.Header.ROMHash[:]); err != nil {
+		return nil, fmt.Errorf("failed to read ROM hash: %w", err)
+	}
+	model, err := readBlob[uint8](br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model: %w", err)
+	}
+	movie.Header.Model = string(model)
+	if _, err := io.ReadFull(br, movie.Header.BootROMHash[:]); err != nil {
+		return nil, fmt.Errorf("failed to read boot ROM hash: %w", err)
+	}
+	movie.Header.StartState, err = readBlob[uint32](br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read start state: %w", err)
+	}
+	frames, err := readBlob[uint32](br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frames: %w", err)
+	}
+	movie.Frames = make([]Button, len(frames))
+	for i, frame := range frames {
+		movie.Frames[i] = Button(frame)
+	}
+
+	return movie, nil
+}
+
+// Write encodes the movie to w.
+func (m *Movie) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	frames := make([]byte, len(m.Frames))
+	for i, frame := range m.Frames {
+		frames[i] = byte(frame)
+	}
+
+	if _, err := bw.Write(movieMagic); err != nil {
+		return fmt.Errorf("failed to write movie magic: %w", err)
+	}
+	if err := bw.WriteByte(movieVersion); err != nil {
+		return fmt.Errorf("failed to write movie version: %w", err)
+	}
+	if _, err := bw.Write(m.Header.ROMHash[:]); err != nil {
+		return fmt.Errorf("failed to write ROM hash: %w", err)
+	}
+	if err := writeBlob[uint8](bw, []byte(m.Header.Model)); err != nil {
+		return fmt.Errorf("failed to write model: %w", err)
+	}
+	if _, err := bw.Write(m.Header.BootROMHash[:]); err != nil {
+		return fmt.Errorf("failed to write boot ROM hash: %w", err)
+	}
+	if err := writeBlob[uint32](bw, m.Header.StartState); err != nil {
+		return fmt.Errorf("failed to write start state: %w", err)
+	}
+	if err := writeBlob[uint32](bw, frames); err != nil {
+		return fmt.Errorf("failed to write frames: %w", err)
+	}
+
+	return bw.Flush()
+}
+
+// readBlob reads a byte slice prefixed with its length encoded as L.
+func readBlob[L uint8 | uint32](r io.Reader) ([]byte, error) {
+	var length L
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if uint64(length) > maxMovieBlob {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMovieTooLarge, length)
+	}
+	// Read what's there rather than trusting the length, which may be
+	// larger than the file
+	data, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+// writeBlob writes a byte slice prefixed with its length encoded as L.
+func writeBlob[L uint8 | uint32](w io.Writer, data []byte) error {
+	if err := binary.Write(w, binary.LittleEndian, L(len(data))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
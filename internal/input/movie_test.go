@@ -0,0 +1,98 @@
+package input_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+)
+
+func TestMovieRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	movie := &input.Movie{
+		Header: input.MovieHeader{
+			ROMHash:     sha256.Sum256([]byte("rom")),
+			Model:       "DMG",
+			BootROMHash: sha256.Sum256([]byte("bios")),
+			StartState:  []byte{0x01, 0x02, 0x03},
+		},
+		Frames: []input.Button{0, input.ButtonStart, input.ButtonA | input.ButtonRight, 0xFF},
+	}
+
+	var buf bytes.Buffer
+	if err := movie.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := input.ReadMovie(&buf)
+	if err != nil {
+		t.Fatalf("ReadMovie() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, movie) {
+		t.Errorf("ReadMovie() = %+v, want %+v", got, movie)
+	}
+}
+
+func TestReadMovieInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := input.ReadMovie(bytes.NewReader([]byte("NOPE\x01")))
+	if !errors.Is(err, input.ErrInvalidMovie) {
+		t.Errorf("ReadMovie() error = %v, want %v", err, input.ErrInvalidMovie)
+	}
+}
+
+func TestReadMovieTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	buf.WriteString("GBMV\x01")
+	buf.Write(make([]byte, sha256.Size))
+	buf.WriteString("\x03DMG")
+	buf.Write(make([]byte, sha256.Size))
+	buf.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF}) // 4 GiB start state
+	_, err := input.ReadMovie(&buf)
+	if !errors.Is(err, input.ErrMovieTooLarge) {
+		t.Errorf("ReadMovie() error = %v, want %v", err, input.ErrMovieTooLarge)
+	}
+
+	// A length within the limit that's longer than the file
+	buf.Reset()
+	buf.WriteString("GBMV\x01")
+	buf.Write(make([]byte, sha256.Size))
+	buf.WriteString("\x03DMG")
+	buf.Write(make([]byte, sha256.Size))
+	buf.Write([]byte{0x00, 0x00, 0x00, 0x01, 0x42}) // 16 MiB start state
+	if _, err := input.ReadMovie(&buf); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadMovie() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+type nopCPU struct{}
+
+func (nopCPU) SetInterruptFlag(_ impls.Interrupt, _ bool) {}
+
+func TestPlayAndRecord(t *testing.T) {
+	t.Parallel()
+
+	movie := &input.Movie{Frames: []input.Button{input.ButtonA, input.ButtonB, input.ButtonStart}}
+	in := input.NewInput(nopCPU{})
+	recording := &input.Movie{}
+	in.Play(movie)
+	in.Record(recording)
+	for range len(movie.Frames) + 1 {
+		in.NextFrame()
+	}
+	if in.Playing() {
+		t.Errorf("Playing() = true after the movie ended")
+	}
+	want := append(append([]input.Button{}, movie.Frames...), 0)
+	if !reflect.DeepEqual(recording.Frames, want) {
+		t.Errorf("recorded frames = %v, want %v", recording.Frames, want)
+	}
+}
@@ -0,0 +1,123 @@
+package input
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidScript = errors.New("invalid input script")
+	ErrScriptTooLong = errors.New("input script is too long")
+)
+
+// MaxScriptFrames is how many frames an input script can last, about an hour,
+// so a mistyped frame number can't allocate without bound.
+const MaxScriptFrames = 60 * 60 * 60
+
+//nolint:gochecknoglobals
+var buttonNames = map[string]Button{
+	"right":  ButtonRight,
+	"left":   ButtonLeft,
+	"up":     ButtonUp,
+	"down":   ButtonDown,
+	"a":      ButtonA,
+	"b":      ButtonB,
+	"select": ButtonSelect,
+	"start":  ButtonStart,
+}
+
+// ParseScript compiles a text input script into a movie without a header.
+// Each non-empty line presses buttons starting at a frame, for one frame
+// unless a duration is given:
+//
+//	# Skip the title screen, then hold right while jumping
+//	frame 120: press START for 5 frames
+//	frame 300: press RIGHT for 60 frames
+//	frame 310: press A+RIGHT
+//
+// Overlapping presses are combined, and text after '#' is ignored.
+func ParseScript(r io.Reader) (*Movie, error) {
+	movie := &Movie{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		start, length, buttons, err := parseScriptLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidScript, line, err)
+		}
+		for len(movie.Frames) < start+length {
+			movie.Frames = append(movie.Frames, 0)
+		}
+		for frame := start; frame < start+length; frame++ {
+			movie.Frames[frame] |= buttons
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input script: %w", err)
+	}
+	return movie, nil
+}
+
+func parseScriptLine(text string) (int, int, Button, error) {
+	when, action, ok := strings.Cut(text, ":")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("expected \"frame <n>: <action>\"")
+	}
+
+	whenFields := strings.Fields(when)
+	if len(whenFields) != 2 || !strings.EqualFold(whenFields[0], "frame") {
+		return 0, 0, 0, fmt.Errorf("expected \"frame <n>\", got %q", strings.TrimSpace(when))
+	}
+	start, err := strconv.Atoi(whenFields[1])
+	if err != nil || start < 0 {
+		return 0, 0, 0, fmt.Errorf("invalid frame number %q", whenFields[1])
+	}
+
+	actionFields := strings.Fields(action)
+	if len(actionFields) < 2 || !strings.EqualFold(actionFields[0], "press") {
+		return 0, 0, 0, fmt.Errorf("expected \"press <buttons>\", got %q", strings.TrimSpace(action))
+	}
+
+	var buttons Button
+	for _, name := range strings.Split(actionFields[1], "+") {
+		button, ok := buttonNames[strings.ToLower(name)]
+		if !ok {
+			return 0, 0, 0, fmt.Errorf("unknown button %q", name)
+		}
+		buttons |= button
+	}
+
+	length := 1
+	switch len(actionFields) {
+	case 2:
+	case 4, 5:
+		if !strings.EqualFold(actionFields[2], "for") {
+			return 0, 0, 0, fmt.Errorf("expected \"for <n> frames\"")
+		}
+		length, err = strconv.Atoi(actionFields[3])
+		if err != nil || length < 1 {
+			return 0, 0, 0, fmt.Errorf("invalid frame count %q", actionFields[3])
+		}
+		if len(actionFields) == 5 && !strings.EqualFold(actionFields[4], "frames") && !strings.EqualFold(actionFields[4], "frame") {
+			return 0, 0, 0, fmt.Errorf("expected \"frames\", got %q", actionFields[4])
+		}
+	default:
+		return 0, 0, 0, fmt.Errorf("unexpected %q", strings.Join(actionFields[2:], " "))
+	}
+
+	if start >= MaxScriptFrames || length > MaxScriptFrames-start {
+		return 0, 0, 0, fmt.Errorf("%w: presses must end by frame %d", ErrScriptTooLong, MaxScriptFrames)
+	}
+
+	return start, length, buttons, nil
+}
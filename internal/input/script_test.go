@@ -0,0 +1,52 @@
+package input_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/input"
+)
+
+func TestParseScript(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		want   []input.Button
+		err    error
+	}{
+		{"empty", "# nothing to do\n\n", nil, nil},
+		{"single frame", "frame 2: press START", []input.Button{0, 0, input.ButtonStart}, nil},
+		{"duration", "frame 1: press a for 2 frames", []input.Button{0, input.ButtonA, input.ButtonA}, nil},
+		{"combined", "frame 0: press A+RIGHT for 1 frame", []input.Button{input.ButtonA | input.ButtonRight}, nil},
+		{"overlapping", "frame 0: press B for 2 frames\nframe 1: press UP # jump", []input.Button{input.ButtonB, input.ButtonB | input.ButtonUp}, nil},
+		{"unknown button", "frame 0: press X", nil, input.ErrInvalidScript},
+		{"missing colon", "frame 0 press A", nil, input.ErrInvalidScript},
+		{"bad frame", "frame -1: press A", nil, input.ErrInvalidScript},
+		{"bad duration", "frame 0: press A for zero frames", nil, input.ErrInvalidScript},
+		{"frame too late", "frame 999999999999: press A", nil, input.ErrScriptTooLong},
+		{"duration too long", "frame 100: press A for 215901 frames", nil, input.ErrScriptTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			movie, err := input.ParseScript(strings.NewReader(tt.script))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("ParseScript() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseScript() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(movie.Frames, tt.want) {
+				t.Errorf("ParseScript() frames = %v, want %v", movie.Frames, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package input
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// inputSnapshot is the joypad state stored in save states.
+type inputSnapshot struct {
+	SelectLines byte
+	Buttons     Button
+}
+
+// SaveState writes the joypad state to w. Movies being played or recorded
+// are not part of the state.
+func (s *Input) SaveState(w io.Writer) error {
+	snapshot := inputSnapshot{
+		SelectLines: s.selectLines,
+		Buttons:     s.buttons,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write input state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores joypad state previously written with SaveState.
+func (s *Input) LoadState(r io.Reader) error {
+	var snapshot inputSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read input state: %w", err)
+	}
+	s.selectLines = snapshot.SelectLines
+	s.buttons = snapshot.Buttons
+	return nil
+}
@@ -0,0 +1,32 @@
+package interrupts
+
+import "github.com/USA-RedDragon/go-gb/internal/impls"
+
+// Controller holds the interrupt registers. Devices request interrupts
+// through it, and the CPU dispatches the ones that are enabled.
+type Controller struct {
+	Flag   byte // IF, 0xFF0F: interrupts requested
+	Enable byte // IE, 0xFFFF: interrupts enabled
+}
+
+func (c *Controller) Reset() {
+	c.Flag = 0
+	c.Enable = 0
+}
+
+func (c *Controller) GetInterruptEnableFlag(flag impls.Interrupt) bool {
+	return c.Enable&byte(flag) != 0
+}
+
+func (c *Controller) GetInterruptFlag(flag impls.Interrupt) bool {
+	return c.Flag&byte(flag) != 0
+}
+
+func (c *Controller) SetInterruptFlag(flag impls.Interrupt, val bool) {
+	// IF is latched regardless of IME, which only gates dispatch
+	if val {
+		c.Flag |= byte(flag)
+	} else {
+		c.Flag &^= byte(flag)
+	}
+}
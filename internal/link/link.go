@@ -0,0 +1,170 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+)
+
+const (
+	// Quantum is the number of M-cycles each side runs between sync points.
+	// Both sides only ever observe each other's serial port at sync points,
+	// so transfers land on the same cycle every run.
+	Quantum = 1024
+
+	protocolVersion = 1
+
+	flagTransfer byte = 1 << 0 // A transfer clocked by the sender completed
+)
+
+//nolint:gochecknoglobals
+var helloMagic = []byte("GBLK")
+
+var ErrHandshake = errors.New("link peer is not a compatible go-gb")
+
+// Cable connects the serial ports of two emulators over a TCP connection and
+// runs them in lockstep.
+//
+// Every Quantum M-cycles each side sends the contents of SB and any byte it
+// shifted out with its internal clock during the quantum, then waits for the
+// other side's message for the same quantum. A side driving the clock
+// receives the peer's SB as of the previous sync point, and a side waiting on
+// the external clock receives the driving side's byte at the next sync point.
+type Cable struct {
+	conn   net.Conn
+	cycles uint16
+
+	sent      *byte // Byte shifted out with the internal clock this quantum
+	remoteSB  byte  // Peer's SB at the last sync point
+	pendingIn *byte // Byte shifted in by the peer's clock at the last sync point
+}
+
+// Listen waits for another emulator to connect to addr.
+func Listen(addr string) (*Cable, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for link: %w", err)
+	}
+	defer listener.Close()
+	slog.Info("Waiting for link cable connection", "address", listener.Addr().String())
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept link: %w", err)
+	}
+	return newCable(conn)
+}
+
+// Dial connects to another emulator listening on addr.
+func Dial(addr string) (*Cable, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect link: %w", err)
+	}
+	return newCable(conn)
+}
+
+func newCable(conn net.Conn) (*Cable, error) {
+	hello := append(append([]byte{}, helloMagic...), protocolVersion)
+	if _, err := conn.Write(hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send link handshake: %w", err)
+	}
+	remote := make([]byte, len(hello))
+	if _, err := io.ReadFull(conn, remote); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read link handshake: %w", err)
+	}
+	if !bytes.Equal(hello, remote) {
+		conn.Close()
+		return nil, ErrHandshake
+	}
+	slog.Info("Link cable connected", "remote", conn.RemoteAddr().String())
+	return &Cable{
+		conn:     conn,
+		remoteSB: 0xFF,
+	}, nil
+}
+
+// Exchange is called when the local side has clocked a whole byte out.
+func (c *Cable) Exchange(out byte) byte {
+	c.sent = &out
+	return c.remoteSB
+}
+
+// Poll is called while the local side waits for the peer's clock.
+func (c *Cable) Poll(_ byte) (byte, bool) {
+	if c.pendingIn == nil {
+		return 0, false
+	}
+	in := *c.pendingIn
+	c.pendingIn = nil
+	return in, true
+}
+
+// Tick counts M-cycles and syncs with the peer at the end of every quantum.
+func (c *Cable) Tick(sb byte) {
+	if c.conn == nil {
+		return
+	}
+	c.cycles++
+	if c.cycles < Quantum {
+		return
+	}
+	c.cycles = 0
+
+	if err := c.sync(sb); err != nil {
+		slog.Error("Link cable disconnected", "error", err)
+		c.Close()
+	}
+}
+
+func (c *Cable) sync(sb byte) error {
+	msg := [3]byte{0, sb, 0}
+	if c.sent != nil {
+		msg[0] |= flagTransfer
+		msg[2] = *c.sent
+		c.sent = nil
+	}
+	if _, err := c.conn.Write(msg[:]); err != nil {
+		return fmt.Errorf("failed to send sync: %w", err)
+	}
+	if _, err := io.ReadFull(c.conn, msg[:]); err != nil {
+		return fmt.Errorf("failed to read sync: %w", err)
+	}
+	c.remoteSB = msg[1]
+	// A clock pulse the local side wasn't waiting for is lost
+	c.pendingIn = nil
+	if msg[0]&flagTransfer != 0 {
+		in := msg[2]
+		c.pendingIn = &in
+	}
+	return nil
+}
+
+// Close disconnects the cable. Afterwards the local side sees an open port.
+func (c *Cable) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.remoteSB = 0xFF
+	c.pendingIn = nil
+	return err
+}
+
+// Open listens on listen or connects to connect, whichever is set. It
+// returns a nil Cable if neither is.
+func Open(listen, connect string) (*Cable, error) {
+	switch {
+	case listen != "":
+		return Listen(listen)
+	case connect != "":
+		return Dial(connect)
+	default:
+		return nil, nil //nolint:nilnil
+	}
+}
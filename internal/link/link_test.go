@@ -0,0 +1,96 @@
+package link
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+)
+
+type fakeCPU struct {
+	interrupts impls.Interrupt
+}
+
+func (f *fakeCPU) SetInterruptFlag(flag impls.Interrupt, val bool) {
+	if val {
+		f.interrupts |= flag
+	}
+}
+
+func connectedCables(t *testing.T) (*Cable, *Cable) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	var server *Cable
+	var serverErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr = err
+			return
+		}
+		server, serverErr = newCable(conn)
+	}()
+
+	client, err := Dial(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	wg.Wait()
+	if serverErr != nil {
+		t.Fatalf("failed to accept: %v", serverErr)
+	}
+	return server, client
+}
+
+func TestLockstepTransfer(t *testing.T) {
+	t.Parallel()
+
+	masterCable, slaveCable := connectedCables(t)
+	defer masterCable.Close()
+	defer slaveCable.Close()
+
+	masterCPU, slaveCPU := &fakeCPU{}, &fakeCPU{}
+	master, slave := serial.NewSerial(masterCPU, scheduler.NewScheduler()), serial.NewSerial(slaveCPU, scheduler.NewScheduler())
+	master.SetPeer(masterCable)
+	slave.SetPeer(slaveCable)
+
+	master.Write8(0xFF01, 0x42)
+	master.Write8(0xFF02, 0x81)
+	slave.Write8(0xFF01, 0x99)
+	slave.Write8(0xFF02, 0x80)
+
+	const cycles = 4 * Quantum
+	var wg sync.WaitGroup
+	for _, s := range []*serial.Serial{master, slave} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range cycles {
+				s.Step()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if master.SB != 0x99 {
+		t.Errorf("master SB = 0x%02X, want 0x99", master.SB)
+	}
+	if slave.SB != 0x42 {
+		t.Errorf("slave SB = 0x%02X, want 0x42", slave.SB)
+	}
+	if masterCPU.interrupts != impls.SerialInterrupt || slaveCPU.interrupts != impls.SerialInterrupt {
+		t.Errorf("interrupts = %v/%v, want serial on both sides", masterCPU.interrupts, slaveCPU.interrupts)
+	}
+}
@@ -0,0 +1,400 @@
+package machine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/dma"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	"github.com/USA-RedDragon/go-gb/internal/interrupts"
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+	"github.com/USA-RedDragon/go-gb/internal/ppu"
+	"github.com/USA-RedDragon/go-gb/internal/profile"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+	"github.com/USA-RedDragon/go-gb/internal/sound"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+	"github.com/USA-RedDragon/go-gb/internal/timer"
+	"github.com/USA-RedDragon/go-gb/internal/trace"
+)
+
+var (
+	ErrInvalidBIOS = errors.New("invalid BIOS size")
+	ErrNoCartridge = errors.New("a cartridge is required without a BIOS")
+)
+
+// Machine is a whole Game Boy: the CPU and the devices on its bus, wired
+// together through the memory map and the scheduler.
+type Machine struct {
+	config     *config.Config
+	cartridge  *cartridge.Cartridge
+	mmio       memory.MMIO // The memory map
+	scheduler  *scheduler.Scheduler
+	CPU        *cpu.SM83
+	Interrupts *interrupts.Controller
+	PPU        *ppu.PPU
+	Sound      *sound.Sound
+	Input      *input.Input
+	Serial     *serial.Serial
+	Timer      *timer.Timer
+	DMA        *dma.DMA
+	bios       []byte            // Boot ROM, nil to start from its final state
+	biosHash   [sha256.Size]byte // SHA-256 of the loaded BIOS, zero without one
+
+	RAM  [consts.RAMSize]byte  // 8KB of RAM
+	HRAM [consts.HRAMSize]byte // 127 bytes of HRAM
+	bank byte                  // 0xFF50, used to disable BIOS
+
+	cycles uint64 // M-cycles run since the machine was created
+	exit   bool
+
+	stepPC   uint16    // PC of the instruction being stepped
+	hit      *WatchHit // Watchpoint hit by the last step, if any
+	midFrame bool      // Set if RunUntilFrame stopped at a watchpoint
+	resumePC uint16    // PC last stopped at by an execute watchpoint
+	resuming bool      // Set if the next step runs the instruction at resumePC
+
+	symbols  *symbols.Table    // Labels of the ROM, if loaded
+	tracer   *trace.Writer     // Instruction trace, if any
+	profiler *profile.Profiler // Profiler, if any
+	calls    []cpu.Frame       // Scratch space for the call stack being profiled
+}
+
+// New creates a machine for the cartridge, which may be nil to run the boot
+// ROM alone, with the boot ROM at config.BIOS if one is set.
+func New(config *config.Config, cartridge *cartridge.Cartridge) (*Machine, error) {
+	var bios []byte
+	if config.BIOS != "" {
+		var err error
+		bios, err = os.ReadFile(config.BIOS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load BIOS: %w", err)
+		}
+	}
+	return NewWithBIOS(config, cartridge, bios)
+}
+
+// NewWithBIOS creates a machine for the cartridge that boots from the given
+// boot ROM, or starts from the state the boot ROM leaves behind if bios is
+// nil. config.BIOS is ignored.
+func NewWithBIOS(config *config.Config, cartridge *cartridge.Cartridge, bios []byte) (*Machine, error) {
+	if bios != nil && len(bios) != consts.BIOSSize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidBIOS, consts.BIOSSize, len(bios))
+	}
+	if bios == nil && cartridge == nil {
+		return nil, ErrNoCartridge
+	}
+	m := &Machine{
+		config:     config,
+		cartridge:  cartridge,
+		bios:       bios,
+		scheduler:  scheduler.NewScheduler(),
+		Interrupts: &interrupts.Controller{},
+		Sound:      sound.NewSound(),
+	}
+	if bios != nil {
+		m.biosHash = sha256.Sum256(bios)
+	}
+	m.CPU = cpu.NewSM83(config, m, m.Interrupts)
+	m.Input = input.NewInput(m.Interrupts)
+
+	// Components clocked by the scheduler, in the order they run each cycle
+	m.PPU = ppu.NewPPU(m.Interrupts, m.scheduler)
+	m.PPU.DoctorLY = config.TraceDoctor
+	m.Serial = serial.NewSerial(m.Interrupts, m.scheduler)
+	m.Timer = timer.NewTimer(m.Interrupts, m.scheduler)
+	m.DMA = dma.NewDMA(m.mmio.Unwatched(), &m.PPU.OAM, m.scheduler)
+	m.mmio.OnWatch = m.watched
+
+	m.Reset()
+
+	m.PPU.Step()
+	m.PPU.Step()
+	m.PPU.Step()
+	m.PPU.Step()
+
+	slog.Debug("ROM loaded", "rom", cartridge)
+
+	return m, nil
+}
+
+// Reset powers the machine back on, mapping the boot ROM if one is set and
+// otherwise starting from the state the boot ROM leaves behind.
+func (m *Machine) Reset() {
+	m.RAM = [consts.RAMSize]byte{}
+	m.PPU.Reset()
+	m.Input.Reset()
+	m.Serial.Reset()
+	m.Timer.Reset()
+	m.DMA.Reset()
+	if m.cartridge != nil {
+		m.cartridge.Reset()
+	}
+	m.HRAM = [consts.HRAMSize]byte{}
+	m.Interrupts.Reset()
+	m.bank = 0x00
+
+	m.mmio.Unmap()
+
+	if m.bios != nil {
+		// We need to add the BIOS to the memory map at 0x0000, and only add cartridge ROM banks after that
+		m.mmio.AddMMIO(m.bios, 0x0000, consts.BIOSSize, true)
+		if m.cartridge != nil {
+			m.mmio.AddMMIO(m.cartridge.ROMBank0[consts.BIOSSize:], 0x0100, consts.ROMBankSize-consts.BIOSSize, true)
+		} else {
+			m.mmio.AddMMIO(bytes.Repeat([]byte{0xff}, consts.ROMBankSize-consts.BIOSSize), 0x0100, consts.ROMBankSize-consts.BIOSSize, true)
+		}
+	} else {
+		if m.cartridge != nil {
+			m.mmio.AddMMIO(m.cartridge.ROMBank0[:], 0x0, consts.ROMBankSize, true)
+		} else {
+			m.mmio.AddMMIO(bytes.Repeat([]byte{0xff}, consts.ROMBankSize), 0x0, consts.ROMBankSize, true)
+		}
+	}
+	if m.cartridge != nil && len(m.cartridge.AdditionalROMBanks) > 0 {
+		m.mmio.AddMMIO(m.cartridge.AdditionalROMBanks[0][:], 0x4000, consts.ROMBankSize, true)
+	}
+	m.mmio.AddMMIO(m.PPU.VRAM[:], 0x8000, consts.VRAMSize, false)
+	if m.cartridge != nil && m.cartridge.RAMSize.Bytes() > 0 {
+		m.mmio.AddMMIO(m.cartridge.CartridgeRAMBanks[0], 0xA000, consts.CartridgeRAMBankSize, false)
+	} else {
+		m.mmio.AddMMIO(bytes.Repeat([]byte{0xff}, consts.CartridgeRAMBankSize), 0xA000, consts.CartridgeRAMBankSize, false)
+	}
+	m.mmio.AddMMIO(m.RAM[:], 0xC000, consts.RAMSize, false)
+	m.mmio.AddMMIO(m.PPU.OAM[:], 0xFE00, consts.OAMSize, false)
+	m.mmio.AddMMIO(make([]byte, consts.ProhibitedSize), 0xFEA0, consts.ProhibitedSize, false)
+	m.mmio.AddMMIODevice(m.Input, 0xFF00, 1)
+	m.mmio.AddMMIODevice(m.Serial, 0xFF01, 2)
+	m.mmio.AddMMIODevice(m.Timer, 0xFF04, 4)
+	m.mmio.AddMMIOByte(&m.Interrupts.Flag, 0xFF0F, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR10, 0xFF10, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR11, 0xFF11, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR12, 0xFF12, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR13, 0xFF13, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR14, 0xFF14, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR22, 0xFF17, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR24, 0xFF19, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR30, 0xFF1A, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR42, 0xFF21, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR44, 0xFF23, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR50, 0xFF24, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR51, 0xFF25, false)
+	m.mmio.AddMMIOByte(&m.Sound.NR52, 0xFF26, false)
+	m.mmio.AddMMIODevice(m.PPU, 0xFF40, 6)
+	m.mmio.AddMMIODevice(m.DMA, 0xFF46, 1)
+	m.mmio.AddMMIODevice(m.PPU, 0xFF47, 5)
+	m.mmio.AddMMIOByte(&m.bank, 0xFF50, false)
+	byt := byte(0x00)
+	m.mmio.AddMMIOByte(&byt, 0xFF7F, false) // Unused
+	m.mmio.AddMMIO(m.HRAM[:], 0xFF80, consts.HRAMSize, false)
+	m.mmio.AddMMIOByte(&m.Interrupts.Enable, 0xFFFF, false)
+
+	m.CPU.Reset()
+	if m.bios == nil {
+		registers := cpu.Registers{
+			A:  0x01,
+			F:  byte(cpu.ZeroFlag),
+			C:  0x13,
+			E:  0xD8,
+			H:  0x01,
+			L:  0x4D,
+			PC: 0x0100, // Program Counter starts at 0x0100
+			SP: 0xFFFE, // Stack Pointer starts at 0xFFFE
+		}
+		if m.cartridge.ROMBank0[0x014D] == 0x00 {
+			// Header checksum, if zero the Carry and Half Carry flags are set
+			registers.F |= byte(cpu.CarryFlag) | byte(cpu.HalfCarryFlag)
+		}
+		m.CPU.SetRegisters(registers)
+	}
+	m.exit = false
+	m.hit = nil
+	m.midFrame = false
+	m.resuming = false
+	m.scheduler.Restart()
+}
+
+// Read reads the memory map for the CPU without taking any time.
+func (m *Machine) Read(addr uint16) (byte, error) {
+	return m.mmio.Read8(addr)
+}
+
+// Write writes to the memory map for the CPU without taking any time.
+func (m *Machine) Write(addr uint16, data byte) error {
+	return m.mmio.Write8(addr, data)
+}
+
+// Peek reads the memory map without taking any time or checking watchpoints.
+// Unmapped addresses read 0xFF.
+func (m *Machine) Peek(addr uint16) byte {
+	data, err := m.mmio.Unwatched().Read8(addr)
+	if err != nil {
+		return 0xFF
+	}
+	return data
+}
+
+// Poke writes to the memory map without taking any time or checking
+// watchpoints.
+func (m *Machine) Poke(addr uint16, data byte) error {
+	return m.mmio.Unwatched().Write8(addr, data)
+}
+
+// ROMBank returns the cartridge ROM bank mapped at 0x4000-0x7FFF. Without a
+// memory bank controller that is always bank 1.
+func (m *Machine) ROMBank() int {
+	return 1
+}
+
+// SetSymbols sets the labels of the ROM, used by Symbolize and to name the
+// PC in trace logs. t may be nil to clear them.
+func (m *Machine) SetSymbols(t *symbols.Table) {
+	m.symbols = t
+	m.CPU.Symbolize = nil
+	if t != nil {
+		m.CPU.Symbolize = m.Symbolize
+	}
+}
+
+// SetTracer writes every instruction run to t, which may be nil to stop
+// tracing.
+func (m *Machine) SetTracer(t *trace.Writer) {
+	m.tracer = t
+	m.CPU.OnInstruction = nil
+	if t != nil {
+		m.CPU.OnInstruction = m.trace
+	}
+}
+
+func (m *Machine) trace() {
+	r := m.CPU.Registers()
+	pcmem := [4]byte{m.Peek(r.PC), m.Peek(r.PC + 1), m.Peek(r.PC + 2), m.Peek(r.PC + 3)}
+	m.tracer.Trace(r, pcmem, m.cycles)
+}
+
+// SetProfiler counts the cycles spent in every instruction with p, which may
+// be nil to stop profiling.
+func (m *Machine) SetProfiler(p *profile.Profiler) {
+	m.profiler = p
+}
+
+// Symbols returns the labels of the ROM, or nil if none are loaded.
+func (m *Machine) Symbols() *symbols.Table {
+	return m.symbols
+}
+
+// Symbolize names addr after the nearest label in the banks mapped now, as in
+// PlayerUpdate+3, or returns an empty string if there is none.
+func (m *Machine) Symbolize(addr uint16) string {
+	return m.symbols.Format(m.ROMBank(), addr)
+}
+
+// Tick advances every device by an M-cycle.
+func (m *Machine) Tick() {
+	m.scheduler.Tick()
+}
+
+// Step runs a single CPU instruction and returns the M-cycles it took. If the
+// instruction hit a watchpoint, WatchHit returns it until the next step.
+//
+// An execute watchpoint stops the machine before the instruction runs, like
+// a breakpoint, and Step returns 0. The next step runs it without stopping.
+func (m *Machine) Step() int {
+	m.hit = nil
+	m.stepPC = m.CPU.GetPC()
+	resuming := m.resuming && m.resumePC == m.stepPC
+	m.resuming = false
+	if m.mmio.Watching() && !m.CPU.IsHalted() && !resuming {
+		m.mmio.Watch(m.stepPC, m.Peek(m.stepPC), memory.AccessExecute)
+		if m.hit != nil {
+			m.resumePC = m.stepPC
+			m.resuming = true
+			return 0
+		}
+	}
+
+	if m.profiler != nil {
+		// The instruction is charged to the calls made before it
+		m.calls = m.CPU.AppendCallStack(m.calls[:0])
+	}
+
+	preBank := m.bank
+	cycles := m.CPU.Step()
+	m.cycles += uint64(cycles)
+	if m.profiler != nil {
+		m.profiler.Record(m.ROMBank(), m.stepPC, m.calls, cycles)
+	}
+	if m.bank != preBank && m.bank != 0 {
+		m.disableBIOS()
+	}
+	return cycles
+}
+
+// Cycles returns the M-cycles run since the machine was created. It keeps
+// counting across resets and loaded states.
+func (m *Machine) Cycles() uint64 {
+	return m.cycles
+}
+
+// disableBIOS maps the cartridge over the boot ROM, if one is mapped.
+func (m *Machine) disableBIOS() {
+	if m.bios == nil {
+		return
+	}
+	err := m.mmio.RemoveMMIO(0x0000, consts.BIOSSize)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to remove BIOS MMIO: %v", err))
+	}
+	m.mmio.AddMMIO(m.cartridge.ROMBank0[:consts.BIOSSize], 0x0000, consts.BIOSSize, true)
+}
+
+// RunUntilFrame runs as fast as possible until the PPU completes a frame.
+// Pacing to real time is up to the caller.
+//
+// It stops early if a watchpoint is hit, returning the last complete frame,
+// and the next call picks up the frame where this one stopped.
+func (m *Machine) RunUntilFrame() [consts.FrameBufferSize]byte {
+	if !m.midFrame {
+		m.Input.NextFrame()
+	}
+	m.midFrame = false
+	for !m.PPU.HaveFrame {
+		m.Step()
+		if m.hit != nil {
+			m.midFrame = true
+			break
+		}
+	}
+
+	return m.PPU.GetFrame()
+}
+
+// Run runs until Quit is called or a watchpoint is hit, paced to the
+// configured speed once per frame's worth of cycles.
+func (m *Machine) Run() {
+	pacer := newPacer(m.config.Speed, m.config.Uncapped)
+	cycles := 0
+	for !m.exit {
+		cycles += m.Step()
+		if m.hit != nil {
+			slog.Info("Stopped at watchpoint", "hit", m.hit)
+			return
+		}
+		if cycles >= consts.CyclesPerFrame {
+			cycles -= consts.CyclesPerFrame
+			pacer.wait()
+		}
+	}
+}
+
+func (m *Machine) Quit() {
+	m.exit = true
+}
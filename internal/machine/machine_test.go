@@ -0,0 +1,44 @@
+package machine_test
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+)
+
+func TestNewBIOSErrors(t *testing.T) {
+	t.Parallel()
+
+	missing := filepath.Join(t.TempDir(), "missing.bin")
+	tests := []struct {
+		name string
+		new  func() error
+		want error
+	}{
+		{"missing BIOS", func() error {
+			_, err := machine.New(&config.Config{BIOS: missing}, nil)
+			return err
+		}, fs.ErrNotExist},
+		{"short BIOS", func() error {
+			_, err := machine.NewWithBIOS(&config.Config{}, nil, make([]byte, 16))
+			return err
+		}, machine.ErrInvalidBIOS},
+		{"no BIOS or cartridge", func() error {
+			_, err := machine.New(&config.Config{}, nil)
+			return err
+		}, machine.ErrNoCartridge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.new(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
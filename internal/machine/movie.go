@@ -0,0 +1,60 @@
+package machine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+)
+
+var (
+	ErrMovieROMMismatch   = errors.New("movie was recorded with a different ROM")
+	ErrMovieModelMismatch = errors.New("movie was recorded on a different model")
+	ErrMovieBIOSMismatch  = errors.New("movie was recorded with a different BIOS")
+)
+
+// MovieHeader describes the machine as it is set up now, from power-on.
+func (m *Machine) MovieHeader() input.MovieHeader {
+	header := input.MovieHeader{
+		Model:       consts.ModelDMG,
+		BootROMHash: m.biosHash,
+	}
+	if m.cartridge != nil {
+		header.ROMHash = m.cartridge.Hash
+	}
+	return header
+}
+
+// RecordMovie resets the machine and records its input from power-on.
+func (m *Machine) RecordMovie() *input.Movie {
+	m.Reset()
+	movie := &input.Movie{Header: m.MovieHeader()}
+	m.Input.Record(movie)
+	return movie
+}
+
+// PlayMovie resets the machine, or loads the movie's start state, and plays
+// movie back. The movie must have been recorded on the same machine for
+// playback to be bit-exact.
+func (m *Machine) PlayMovie(movie *input.Movie) error {
+	header := m.MovieHeader()
+	switch {
+	case movie.Header.ROMHash != header.ROMHash:
+		return ErrMovieROMMismatch
+	case movie.Header.Model != header.Model:
+		return ErrMovieModelMismatch
+	case movie.Header.BootROMHash != header.BootROMHash:
+		return ErrMovieBIOSMismatch
+	}
+	if len(movie.Header.StartState) > 0 {
+		if err := m.LoadState(bytes.NewReader(movie.Header.StartState)); err != nil {
+			return fmt.Errorf("failed to load start state: %w", err)
+		}
+	} else {
+		m.Reset()
+	}
+	m.Input.Play(movie)
+	return nil
+}
@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+)
+
+// maxLag is how far behind real time emulation may fall before the pacer
+// stops trying to catch up.
+const maxLag = 100 * time.Millisecond
+
+// pacer keeps emulation in step with real time by sleeping once per frame.
+// Sleeping after every cycle doesn't work, as the Go scheduler can't honour
+// microsecond sleeps.
+type pacer struct {
+	frameTime time.Duration // Zero when uncapped
+	next      time.Time     // When the frame being emulated is due to end
+}
+
+// newPacer creates a pacer running at speed times real time, or as fast as
+// possible if uncapped.
+func newPacer(speed float64, uncapped bool) *pacer {
+	p := &pacer{
+		next: time.Now(),
+	}
+	if !uncapped && speed > 0 {
+		p.frameTime = time.Duration(float64(time.Second) / (consts.FrameRate * speed))
+	}
+	return p
+}
+
+// wait sleeps until the frame just emulated is due to end.
+func (p *pacer) wait() {
+	if p.frameTime == 0 {
+		return
+	}
+	p.next = p.next.Add(p.frameTime)
+	now := time.Now()
+	if now.Sub(p.next) > maxLag {
+		p.next = now
+	}
+	time.Sleep(p.next.Sub(now))
+}
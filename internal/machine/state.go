@@ -0,0 +1,205 @@
+package machine
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+)
+
+const stateVersion = 2
+
+//nolint:gochecknoglobals
+var stateMagic = []byte("GBSS")
+
+var (
+	ErrInvalidState            = errors.New("not a go-gb save state")
+	ErrUnsupportedStateVersion = errors.New("unsupported save state version")
+	ErrStateROMMismatch        = errors.New("save state was made with a different ROM")
+)
+
+// cpuSnapshot is the CPU state stored in save states.
+type cpuSnapshot struct {
+	A, F, B, C, D, E, H, L byte
+	PC, SP                 uint16
+	IME                    bool
+	InterruptFlag          byte
+	InterruptEnable        byte
+	Bank                   byte
+	Halted                 bool
+	RAM                    [consts.RAMSize]byte
+	HRAM                   [consts.HRAMSize]byte
+}
+
+// SaveState writes a snapshot of the whole machine to w. Loading it with
+// LoadState and running on is bit-identical to running on from here.
+func (m *Machine) SaveState(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.Write(stateMagic); err != nil {
+		return fmt.Errorf("failed to write save state magic: %w", err)
+	}
+	if err := bw.WriteByte(stateVersion); err != nil {
+		return fmt.Errorf("failed to write save state version: %w", err)
+	}
+	romHash := m.MovieHeader().ROMHash
+	if _, err := bw.Write(romHash[:]); err != nil {
+		return fmt.Errorf("failed to write ROM hash: %w", err)
+	}
+
+	registers := m.CPU.Registers()
+	snapshot := cpuSnapshot{
+		A:               registers.A,
+		F:               registers.F,
+		B:               registers.B,
+		C:               registers.C,
+		D:               registers.D,
+		E:               registers.E,
+		H:               registers.H,
+		L:               registers.L,
+		PC:              registers.PC,
+		SP:              registers.SP,
+		IME:             registers.IME,
+		InterruptFlag:   m.Interrupts.Flag,
+		InterruptEnable: m.Interrupts.Enable,
+		Bank:            m.bank,
+		Halted:          m.CPU.IsHalted(),
+		RAM:             m.RAM,
+		HRAM:            m.HRAM,
+	}
+	if err := binary.Write(bw, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write CPU state: %w", err)
+	}
+	if err := m.PPU.SaveState(bw); err != nil {
+		return err
+	}
+	if err := m.Sound.SaveState(bw); err != nil {
+		return err
+	}
+	if err := m.Input.SaveState(bw); err != nil {
+		return err
+	}
+	if err := m.Serial.SaveState(bw); err != nil {
+		return err
+	}
+	if err := m.Timer.SaveState(bw); err != nil {
+		return err
+	}
+	if err := m.DMA.SaveState(bw); err != nil {
+		return err
+	}
+	if m.cartridge != nil {
+		if err := m.cartridge.SaveState(bw); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// LoadState replaces the state of the whole machine with a snapshot written
+// by SaveState. If the snapshot can't be loaded the machine is left as it was.
+func (m *Machine) LoadState(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(stateMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return fmt.Errorf("failed to read save state magic: %w", err)
+	}
+	if !bytes.Equal(magic, stateMagic) {
+		return ErrInvalidState
+	}
+	version, err := br.ReadByte()
+	if err != nil {
+		return fmt.Errorf("failed to read save state version: %w", err)
+	}
+	if version != stateVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedStateVersion, version)
+	}
+	var romHash [sha256.Size]byte
+	if _, err := io.ReadFull(br, romHash[:]); err != nil {
+		return fmt.Errorf("failed to read ROM hash: %w", err)
+	}
+	if romHash != m.MovieHeader().ROMHash {
+		return ErrStateROMMismatch
+	}
+
+	var backup bytes.Buffer
+	if err := m.SaveState(&backup); err != nil {
+		return fmt.Errorf("failed to back up state: %w", err)
+	}
+	if err := m.loadSubsystems(br); err != nil {
+		// Skip the header, which was already checked when it was saved
+		backup.Next(len(stateMagic) + 1 + sha256.Size)
+		if restoreErr := m.loadSubsystems(&backup); restoreErr != nil {
+			panic(fmt.Sprintf("Failed to restore state after failed load: %v", restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *Machine) loadSubsystems(r io.Reader) error {
+	var snapshot cpuSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read CPU state: %w", err)
+	}
+
+	// Start from power-on so the memory map matches the boot ROM setting
+	m.Reset()
+	m.CPU.SetRegisters(cpu.Registers{
+		A:   snapshot.A,
+		F:   snapshot.F,
+		B:   snapshot.B,
+		C:   snapshot.C,
+		D:   snapshot.D,
+		E:   snapshot.E,
+		H:   snapshot.H,
+		L:   snapshot.L,
+		PC:  snapshot.PC,
+		SP:  snapshot.SP,
+		IME: snapshot.IME,
+	})
+	m.Interrupts.Flag = snapshot.InterruptFlag
+	m.Interrupts.Enable = snapshot.InterruptEnable
+	m.bank = snapshot.Bank
+	if snapshot.Halted {
+		m.CPU.Halt()
+	}
+	m.RAM = snapshot.RAM
+	m.HRAM = snapshot.HRAM
+	if m.bank != 0 {
+		m.disableBIOS()
+	}
+
+	if err := m.PPU.LoadState(r); err != nil {
+		return err
+	}
+	if err := m.Sound.LoadState(r); err != nil {
+		return err
+	}
+	if err := m.Input.LoadState(r); err != nil {
+		return err
+	}
+	if err := m.Serial.LoadState(r); err != nil {
+		return err
+	}
+	if err := m.Timer.LoadState(r); err != nil {
+		return err
+	}
+	if err := m.DMA.LoadState(r); err != nil {
+		return err
+	}
+	if m.cartridge != nil {
+		if err := m.cartridge.LoadState(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
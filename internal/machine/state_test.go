@@ -0,0 +1,116 @@
+package machine_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+)
+
+// program turns the LCD on, then keeps incrementing a byte in WRAM and
+// copying it to the background map, tile data and cartridge RAM.
+//
+//nolint:gochecknoglobals
+var program = []byte{
+	0x3E, 0x91, // LD A,0x91
+	0xE0, 0x40, // LDH (0x40),A
+	0x21, 0x00, 0xC0, // LD HL,0xC000
+	0x34,             // INC (HL)
+	0x7E,             // LD A,(HL)
+	0xEA, 0x00, 0x98, // LD (0x9800),A
+	0xEA, 0x10, 0x80, // LD (0x8010),A
+	0xEA, 0x00, 0xA0, // LD (0xA000),A
+	0x18, 0xF0, // JR 0x0104
+}
+
+func newMachine(t *testing.T, variant byte) *machine.Machine {
+	t.Helper()
+
+	rom := make([]byte, 2*16384)
+	copy(rom[0x100:], program)
+	rom[0x149] = 0x02 // 8KB of cartridge RAM
+	rom[0x7FFF] = variant
+
+	path := filepath.Join(t.TempDir(), "test.gb")
+	if err := os.WriteFile(path, rom, 0o600); err != nil {
+		t.Fatalf("failed to write ROM: %v", err)
+	}
+	cart, err := cartridge.NewCartridge(path)
+	if err != nil {
+		t.Fatalf("failed to load cartridge: %v", err)
+	}
+	m, err := machine.New(&config.Config{LogLevel: config.LogLevelInfo}, cart)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func saveState(t *testing.T, m *machine.Machine) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := m.SaveState(&buf); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveStateBitIdentical(t *testing.T) {
+	t.Parallel()
+
+	original := newMachine(t, 0)
+	for range 10 {
+		original.RunUntilFrame()
+	}
+	state := saveState(t, original)
+
+	restored := newMachine(t, 0)
+	if err := restored.LoadState(bytes.NewReader(state)); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if !bytes.Equal(saveState(t, restored), state) {
+		t.Fatal("state differs straight after loading")
+	}
+
+	for frame := range 20 {
+		if original.RunUntilFrame() != restored.RunUntilFrame() {
+			t.Fatalf("frame %d differs after loading", frame)
+		}
+	}
+	if !bytes.Equal(saveState(t, original), saveState(t, restored)) {
+		t.Error("state differs after running on")
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	t.Parallel()
+
+	state := saveState(t, newMachine(t, 0))
+
+	other := newMachine(t, 1)
+	if err := other.LoadState(bytes.NewReader(state)); !errors.Is(err, machine.ErrStateROMMismatch) {
+		t.Errorf("LoadState() error = %v, want %v", err, machine.ErrStateROMMismatch)
+	}
+
+	gb := newMachine(t, 0)
+	for range 3 {
+		gb.RunUntilFrame()
+	}
+	before := saveState(t, gb)
+	if err := gb.LoadState(bytes.NewReader(state[:len(state)-16])); err == nil {
+		t.Error("LoadState() of a truncated state succeeded")
+	}
+	if !bytes.Equal(saveState(t, gb), before) {
+		t.Error("failed load changed the machine")
+	}
+
+	if err := gb.LoadState(bytes.NewReader([]byte("not a state"))); !errors.Is(err, machine.ErrInvalidState) {
+		t.Errorf("LoadState() error = %v, want %v", err, machine.ErrInvalidState)
+	}
+}
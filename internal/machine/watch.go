@@ -0,0 +1,72 @@
+package machine
+
+import (
+	"fmt"
+
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+)
+
+// WatchHit describes the access that stopped the machine at a watchpoint.
+type WatchHit struct {
+	Watchpoint  memory.Watchpoint
+	Addr        uint16
+	Data        byte // Byte read, written or executed
+	Access      memory.Access
+	PC          uint16 // Address of the instruction that made the access
+	Instruction string
+}
+
+func (h WatchHit) String() string {
+	var what string
+	switch h.Access {
+	case memory.AccessRead:
+		what = fmt.Sprintf("read 0x%02X from 0x%04X", h.Data, h.Addr)
+	case memory.AccessWrite:
+		what = fmt.Sprintf("wrote 0x%02X to 0x%04X", h.Data, h.Addr)
+	default:
+		what = fmt.Sprintf("executed 0x%04X", h.Addr)
+	}
+	return fmt.Sprintf("%s at PC 0x%04X (%s)", what, h.PC, h.Instruction)
+}
+
+// AddWatchpoint stops the machine when the CPU accesses memory the watchpoint
+// covers. It returns the watchpoint with its ID assigned.
+func (m *Machine) AddWatchpoint(w memory.Watchpoint) memory.Watchpoint {
+	return m.mmio.AddWatchpoint(w)
+}
+
+// RemoveWatchpoint removes the watchpoint with the given ID and reports
+// whether there was one.
+func (m *Machine) RemoveWatchpoint(id int) bool {
+	return m.mmio.RemoveWatchpoint(id)
+}
+
+// Watchpoints returns the watchpoints set.
+func (m *Machine) Watchpoints() []memory.Watchpoint {
+	return m.mmio.Watchpoints()
+}
+
+// WatchHit returns the watchpoint hit by the last instruction stepped, if any.
+func (m *Machine) WatchHit() *WatchHit {
+	return m.hit
+}
+
+func (m *Machine) watched(w memory.Watchpoint, addr uint16, data byte, access memory.Access) {
+	if m.hit != nil {
+		return
+	}
+	inst := disasm.Decode(m.Peek, m.stepPC)
+	// Fetching the instruction's own bytes doesn't count as reading them
+	if access == memory.AccessRead && addr-m.stepPC < uint16(inst.Len()) {
+		return
+	}
+	m.hit = &WatchHit{
+		Watchpoint:  w,
+		Addr:        addr,
+		Data:        data,
+		Access:      access,
+		PC:          m.stepPC,
+		Instruction: inst.String(),
+	}
+}
@@ -11,8 +11,16 @@ type mmioType int
 const (
 	MMIOTypeByte mmioType = iota
 	MMIOTypeByteArray
+	MMIOTypeDevice
 )
 
+// Device is a memory-mapped peripheral whose registers are computed on access
+// rather than backed by plain bytes.
+type Device interface {
+	Read8(addr uint16) uint8
+	Write8(addr uint16, data uint8)
+}
+
 type mmioMapping struct {
 	address  uint16
 	size     uint16
@@ -21,11 +29,23 @@ type mmioMapping struct {
 	mmioType mmioType
 
 	data     []byte
-	byteData *byte // For single byte MMIO mappings
+	byteData *byte  // For single byte MMIO mappings
+	device   Device // For device MMIO mappings
 }
 
 type MMIO struct {
 	mmios []mmioMapping
+
+	watchpoints []Watchpoint
+	nextWatchID int
+
+	// OnWatch is called when an access matches a watchpoint.
+	OnWatch func(w Watchpoint, addr uint16, data byte, access Access)
+}
+
+// Unmap removes every mapping, keeping the watchpoints.
+func (h *MMIO) Unmap() {
+	h.mmios = nil
 }
 
 func (h *MMIO) mapMemory(addr uint16) uint16 {
@@ -61,7 +81,7 @@ func (h *MMIO) AddMMIO(data []byte, address uint16, size uint16, readOnly bool)
 	// Add the MMIO, but ensure that the entries are sorted by address.
 	// This is required for the MMIO handler to work properly.
 
-	mapping := mmioMapping{address, size, readOnly, MMIOTypeByteArray, data, nil}
+	mapping := mmioMapping{address, size, readOnly, MMIOTypeByteArray, data, nil, nil}
 	h.mmios = append(h.mmios, mapping)
 
 	sort.Slice(h.mmios, func(i, j int) bool {
@@ -72,7 +92,18 @@ func (h *MMIO) AddMMIO(data []byte, address uint16, size uint16, readOnly bool)
 func (h *MMIO) AddMMIOByte(data *byte, address uint16, readOnly bool) {
 	// Add a single byte MMIO mapping.
 	// This is useful for registers that are not larger than 1 byte.
-	mapping := mmioMapping{address, 1, readOnly, MMIOTypeByte, []byte{}, data}
+	mapping := mmioMapping{address, 1, readOnly, MMIOTypeByte, []byte{}, data, nil}
+	h.mmios = append(h.mmios, mapping)
+
+	sort.Slice(h.mmios, func(i, j int) bool {
+		return h.mmios[i].address < h.mmios[j].address
+	})
+}
+
+func (h *MMIO) AddMMIODevice(device Device, address uint16, size uint16) {
+	// Add a device MMIO mapping.
+	// Reads and writes are forwarded to the device with the absolute address.
+	mapping := mmioMapping{address, size, false, MMIOTypeDevice, []byte{}, nil, device}
 	h.mmios = append(h.mmios, mapping)
 
 	sort.Slice(h.mmios, func(i, j int) bool {
@@ -80,8 +111,17 @@ func (h *MMIO) AddMMIOByte(data *byte, address uint16, readOnly bool) {
 	})
 }
 
-// Read8 reads a 8-bit value from the MMIO address space and returns it.
+// Read8 reads a 8-bit value from the MMIO address space and returns it,
+// checking the watchpoints.
 func (h *MMIO) Read8(addr uint16) (uint8, error) {
+	data, err := h.read8(addr)
+	if len(h.watchpoints) != 0 && err == nil {
+		h.Watch(addr, data, AccessRead)
+	}
+	return data, err
+}
+
+func (h *MMIO) read8(addr uint16) (uint8, error) {
 	index, err := h.findMMIOIndex(&addr)
 	if err != nil {
 		return 0, err
@@ -92,14 +132,24 @@ func (h *MMIO) Read8(addr uint16) (uint8, error) {
 	}
 	if h.mmios[index].mmioType == MMIOTypeByte {
 		return *h.mmios[index].byteData, nil
+	} else if h.mmios[index].mmioType == MMIOTypeDevice {
+		return h.mmios[index].device.Read8(addr), nil
 	} else if h.mmios[index].mmioType != MMIOTypeByteArray {
 		return 0, fmt.Errorf("MMIO address %04x is not a byte array", addr)
 	}
 	return h.mmios[index].data[nonMapped], nil
 }
 
-// Write8 writes a 8-bit value to the MMIO address space.
+// Write8 writes a 8-bit value to the MMIO address space, checking the
+// watchpoints.
 func (h *MMIO) Write8(addr uint16, data uint8) error {
+	if len(h.watchpoints) != 0 {
+		h.Watch(addr, data, AccessWrite)
+	}
+	return h.write8(addr, data)
+}
+
+func (h *MMIO) write8(addr uint16, data uint8) error {
 	index, err := h.findMMIOIndex(&addr)
 	if err != nil {
 		return err
@@ -115,6 +165,9 @@ func (h *MMIO) Write8(addr uint16, data uint8) error {
 	if h.mmios[index].mmioType == MMIOTypeByte {
 		*h.mmios[index].byteData = data
 		return nil
+	} else if h.mmios[index].mmioType == MMIOTypeDevice {
+		h.mmios[index].device.Write8(addr, data)
+		return nil
 	} else if h.mmios[index].mmioType != MMIOTypeByteArray {
 		return fmt.Errorf("MMIO address %04x is not a byte array", addr)
 	}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Access is a bitmask of the kinds of access a watchpoint stops on.
+type Access uint8
+
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+	AccessExecute
+)
+
+func (a Access) String() string {
+	var s strings.Builder
+	for _, kind := range []struct {
+		access Access
+		name   byte
+	}{{AccessRead, 'r'}, {AccessWrite, 'w'}, {AccessExecute, 'x'}} {
+		if a&kind.access != 0 {
+			s.WriteByte(kind.name)
+		}
+	}
+	return s.String()
+}
+
+var (
+	ErrInvalidAccess    = errors.New("access must be a combination of r, w and x")
+	ErrInvalidRange     = errors.New("invalid address range")
+	ErrInvalidCondition = errors.New("invalid value condition")
+)
+
+// Condition is a predicate on the byte read, written or executed.
+type Condition struct {
+	Op    string // One of ==, !=, <, <=, > and >=
+	Value byte
+}
+
+// Match reports whether data satisfies the condition.
+func (c Condition) Match(data byte) bool {
+	switch c.Op {
+	case "==":
+		return data == c.Value
+	case "!=":
+		return data != c.Value
+	case "<":
+		return data < c.Value
+	case "<=":
+		return data <= c.Value
+	case ">":
+		return data > c.Value
+	case ">=":
+		return data >= c.Value
+	}
+	return false
+}
+
+func (c Condition) String() string {
+	return fmt.Sprintf("%s0x%02X", c.Op, c.Value)
+}
+
+// Watchpoint stops the CPU when it accesses a range of addresses.
+type Watchpoint struct {
+	ID         int
+	Start, End uint16 // Inclusive
+	Access     Access
+	Condition  *Condition // Only stop if the byte accessed matches, if set
+}
+
+func (w Watchpoint) String() string {
+	s := fmt.Sprintf("%s 0x%04X", w.Access, w.Start)
+	if w.End != w.Start {
+		s += fmt.Sprintf("-0x%04X", w.End)
+	}
+	if w.Condition != nil {
+		s += " " + w.Condition.String()
+	}
+	return s
+}
+
+func (w *Watchpoint) match(addr uint16, data byte, access Access) bool {
+	return w.Access&access != 0 && addr >= w.Start && addr <= w.End &&
+		(w.Condition == nil || w.Condition.Match(data))
+}
+
+// ParseWatchpoint parses a watchpoint written as an access such as rw, an
+// address or inclusive range such as C000-C0FF, and an optional condition
+// such as ==42. Numbers are hex, with or without a 0x or $ prefix.
+func ParseWatchpoint(access, addrs, condition string) (Watchpoint, error) {
+	var w Watchpoint
+	for _, c := range strings.ToLower(access) {
+		switch c {
+		case 'r':
+			w.Access |= AccessRead
+		case 'w':
+			w.Access |= AccessWrite
+		case 'x':
+			w.Access |= AccessExecute
+		default:
+			return w, fmt.Errorf("%w: %s", ErrInvalidAccess, access)
+		}
+	}
+	if w.Access == 0 {
+		return w, ErrInvalidAccess
+	}
+
+	start, end, isRange := strings.Cut(addrs, "-")
+	var err error
+	w.Start, err = parseHex16(start)
+	if err != nil {
+		return w, fmt.Errorf("%w: %s", ErrInvalidRange, addrs)
+	}
+	w.End = w.Start
+	if isRange {
+		w.End, err = parseHex16(end)
+		if err != nil || w.End < w.Start {
+			return w, fmt.Errorf("%w: %s", ErrInvalidRange, addrs)
+		}
+	}
+
+	if condition != "" {
+		op := strings.TrimRight(condition, "0123456789abcdefABCDEFxX$")
+		switch op {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return w, fmt.Errorf("%w: %s", ErrInvalidCondition, condition)
+		}
+		value, err := parseHex16(condition[len(op):])
+		if err != nil || value > 0xFF {
+			return w, fmt.Errorf("%w: %s", ErrInvalidCondition, condition)
+		}
+		w.Condition = &Condition{Op: op, Value: byte(value)}
+	}
+	return w, nil
+}
+
+func parseHex16(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"), "$")
+	value, err := strconv.ParseUint(s, 16, 16)
+	return uint16(value), err
+}
+
+// AddWatchpoint adds a watchpoint and returns it with its ID assigned.
+func (h *MMIO) AddWatchpoint(w Watchpoint) Watchpoint {
+	h.nextWatchID++
+	w.ID = h.nextWatchID
+	h.watchpoints = append(h.watchpoints, w)
+	return w
+}
+
+// RemoveWatchpoint removes the watchpoint with the given ID and reports
+// whether there was one.
+func (h *MMIO) RemoveWatchpoint(id int) bool {
+	for i, w := range h.watchpoints {
+		if w.ID == id {
+			h.watchpoints = append(h.watchpoints[:i], h.watchpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Watching reports whether any watchpoints are set.
+func (h *MMIO) Watching() bool {
+	return len(h.watchpoints) != 0
+}
+
+// Watchpoints returns the watchpoints set.
+func (h *MMIO) Watchpoints() []Watchpoint {
+	return append([]Watchpoint{}, h.watchpoints...)
+}
+
+// Watch calls OnWatch for the first watchpoint matching an access. Reads and
+// writes through Read8 and Write8 are checked automatically, but executing an
+// instruction has to be reported here.
+func (h *MMIO) Watch(addr uint16, data byte, access Access) {
+	if h.OnWatch == nil {
+		return
+	}
+	for i := range h.watchpoints {
+		if h.watchpoints[i].match(addr, data, access) {
+			h.OnWatch(h.watchpoints[i], addr, data, access)
+			return
+		}
+	}
+}
+
+// Unwatched reads and writes the memory map without checking watchpoints, for
+// accesses made by the emulator rather than the CPU.
+type Unwatched struct {
+	mmio *MMIO
+}
+
+// Unwatched returns a view of the memory map that skips watchpoints.
+func (h *MMIO) Unwatched() Unwatched {
+	return Unwatched{mmio: h}
+}
+
+func (u Unwatched) Read8(addr uint16) (uint8, error) {
+	return u.mmio.read8(addr)
+}
+
+func (u Unwatched) Write8(addr uint16, data uint8) error {
+	return u.mmio.write8(addr, data)
+}
@@ -0,0 +1,157 @@
+package memory_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+)
+
+func TestParseWatchpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		access    string
+		addrs     string
+		condition string
+		want      memory.Watchpoint
+		err       error
+	}{
+		{name: "read", access: "r", addrs: "C000", want: memory.Watchpoint{Start: 0xC000, End: 0xC000, Access: memory.AccessRead}},
+		{name: "write", access: "w", addrs: "C000", want: memory.Watchpoint{Start: 0xC000, End: 0xC000, Access: memory.AccessWrite}},
+		{name: "execute", access: "x", addrs: "0150", want: memory.Watchpoint{Start: 0x0150, End: 0x0150, Access: memory.AccessExecute}},
+		{name: "read and write", access: "RW", addrs: "C000", want: memory.Watchpoint{Start: 0xC000, End: 0xC000, Access: memory.AccessRead | memory.AccessWrite}},
+		{name: "bad access", access: "bad", addrs: "C000", err: memory.ErrInvalidAccess},
+		{name: "no access", access: "", addrs: "C000", err: memory.ErrInvalidAccess},
+		{name: "range", access: "r", addrs: "C000-C0FF", want: memory.Watchpoint{Start: 0xC000, End: 0xC0FF, Access: memory.AccessRead}},
+		{name: "prefixes", access: "r", addrs: "$C000-0xC0FF", want: memory.Watchpoint{Start: 0xC000, End: 0xC0FF, Access: memory.AccessRead}},
+		{name: "reversed range", access: "r", addrs: "C0FF-C000", err: memory.ErrInvalidRange},
+		{name: "bad address", access: "r", addrs: "wram", err: memory.ErrInvalidRange},
+		{name: "address too big", access: "r", addrs: "10000", err: memory.ErrInvalidRange},
+		{name: "bad range end", access: "r", addrs: "C000-", err: memory.ErrInvalidRange},
+		{
+			name: "condition", access: "w", addrs: "C000", condition: "==$2A",
+			want: memory.Watchpoint{Start: 0xC000, End: 0xC000, Access: memory.AccessWrite, Condition: &memory.Condition{Op: "==", Value: 0x2A}},
+		},
+		{
+			name: "condition with 0x", access: "w", addrs: "C000", condition: ">=0xFF",
+			want: memory.Watchpoint{Start: 0xC000, End: 0xC000, Access: memory.AccessWrite, Condition: &memory.Condition{Op: ">=", Value: 0xFF}},
+		},
+		{name: "condition too big", access: "w", addrs: "C000", condition: "==100", err: memory.ErrInvalidCondition},
+		{name: "bad condition op", access: "w", addrs: "C000", condition: "=42", err: memory.ErrInvalidCondition},
+		{name: "condition without value", access: "w", addrs: "C000", condition: "!=", err: memory.ErrInvalidCondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := memory.ParseWatchpoint(tt.access, tt.addrs, tt.condition)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseWatchpoint() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if got.Start != tt.want.Start || got.End != tt.want.End || got.Access != tt.want.Access {
+				t.Errorf("ParseWatchpoint() = %v, want %v", got, tt.want)
+			}
+			if (got.Condition == nil) != (tt.want.Condition == nil) ||
+				got.Condition != nil && *got.Condition != *tt.want.Condition {
+				t.Errorf("ParseWatchpoint() condition = %v, want %v", got.Condition, tt.want.Condition)
+			}
+		})
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		op   string
+		want [3]bool // Whether 0x41, 0x42 and 0x43 match against 0x42
+	}{
+		{op: "==", want: [3]bool{false, true, false}},
+		{op: "!=", want: [3]bool{true, false, true}},
+		{op: "<", want: [3]bool{true, false, false}},
+		{op: "<=", want: [3]bool{true, true, false}},
+		{op: ">", want: [3]bool{false, false, true}},
+		{op: ">=", want: [3]bool{false, true, true}},
+		{op: "=", want: [3]bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			t.Parallel()
+
+			c := memory.Condition{Op: tt.op, Value: 0x42}
+			for i, data := range []byte{0x41, 0x42, 0x43} {
+				if got := c.Match(data); got != tt.want[i] {
+					t.Errorf("Match(0x%02X) = %t, want %t", data, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	type hit struct {
+		id     int
+		addr   uint16
+		data   byte
+		access memory.Access
+	}
+	var hits []hit
+	mmio := &memory.MMIO{}
+	mmio.AddMMIO(make([]byte, 0x2000), 0xC000, 0x2000, false)
+	mmio.OnWatch = func(w memory.Watchpoint, addr uint16, data byte, access memory.Access) {
+		hits = append(hits, hit{id: w.ID, addr: addr, data: data, access: access})
+	}
+	writes, err := memory.ParseWatchpoint("w", "C000-C00F", "")
+	if err != nil {
+		t.Fatalf("ParseWatchpoint() error = %v", err)
+	}
+	reads, err := memory.ParseWatchpoint("r", "C100", ">1")
+	if err != nil {
+		t.Fatalf("ParseWatchpoint() error = %v", err)
+	}
+	writes = mmio.AddWatchpoint(writes)
+	reads = mmio.AddWatchpoint(reads)
+
+	steps := []struct {
+		name string
+		run  func() error
+		want *hit
+	}{
+		{name: "write in range", run: func() error { return mmio.Write8(0xC00F, 0x12) }, want: &hit{writes.ID, 0xC00F, 0x12, memory.AccessWrite}},
+		{name: "write out of range", run: func() error { return mmio.Write8(0xC010, 0x12) }},
+		{name: "read of a write watchpoint", run: func() error { _, err := mmio.Read8(0xC00F); return err }},
+		{name: "unwatched write", run: func() error { return mmio.Unwatched().Write8(0xC000, 0x34) }},
+		{name: "write of a read watchpoint", run: func() error { return mmio.Write8(0xC100, 0x01) }},
+		{name: "read not matching", run: func() error { _, err := mmio.Read8(0xC100); return err }},
+		{name: "setup", run: func() error { return mmio.Unwatched().Write8(0xC100, 0x02) }},
+		{name: "unwatched read", run: func() error { _, err := mmio.Unwatched().Read8(0xC100); return err }},
+		{name: "read matching", run: func() error { _, err := mmio.Read8(0xC100); return err }, want: &hit{reads.ID, 0xC100, 0x02, memory.AccessRead}},
+		{name: "execute", run: func() error { mmio.Watch(0xC100, 0x02, memory.AccessExecute); return nil }},
+	}
+	for _, step := range steps {
+		hits = hits[:0]
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		switch {
+		case step.want == nil && len(hits) != 0:
+			t.Errorf("%s: OnWatch called with %+v", step.name, hits)
+		case step.want != nil && (len(hits) != 1 || hits[0] != *step.want):
+			t.Errorf("%s: OnWatch called with %+v, want %+v", step.name, hits, *step.want)
+		}
+	}
+
+	if !mmio.RemoveWatchpoint(writes.ID) || mmio.RemoveWatchpoint(writes.ID) {
+		t.Error("RemoveWatchpoint() didn't remove the watchpoint exactly once")
+	}
+	if got := mmio.Watchpoints(); len(got) != 1 || got[0].ID != reads.ID {
+		t.Errorf("Watchpoints() = %v, want only %v", got, reads)
+	}
+}
@@ -0,0 +1,27 @@
+package ppu
+
+import (
+	"image"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+)
+
+const (
+	ScreenWidth  = 160
+	ScreenHeight = 144
+)
+
+// shades maps each color in the frame buffer to a gray level, from white (0)
+// to black (3).
+//
+//nolint:gochecknoglobals
+var shades = [4]uint8{0xFF, 0xAA, 0x55, 0x00}
+
+// FrameImage converts a frame buffer into a grayscale image.
+func FrameImage(frame [consts.FrameBufferSize]byte) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+	for i, pixel := range frame {
+		img.Pix[i] = shades[pixel&0x03]
+	}
+	return img
+}
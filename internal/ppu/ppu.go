@@ -5,6 +5,7 @@ import (
 
 	"github.com/USA-RedDragon/go-gb/internal/consts"
 	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
 )
 
 type ppuState uint8
@@ -50,7 +51,12 @@ type PPU struct {
 	OBP1       byte                  // OBP1, object palette 1 data
 	OAM        [consts.OAMSize]byte  // OAM, Object Attribute Memory
 	Fetcher    *Fetcher              // Fetcher for pixel data
-	cpu        impls.CPU             // Reference to the CPU for interrupt handling
+	interrupts impls.Interrupts      // Where VBlank interrupts are requested
+	scheduler  *scheduler.Scheduler
+
+	// DoctorLY makes LY always read 0x90, as in the logs Gameboy Doctor
+	// compares instruction traces against.
+	DoctorLY bool
 
 	HaveFrame    bool
 	FrameBufferA [consts.FrameBufferSize]byte
@@ -62,12 +68,14 @@ type PPU struct {
 	disabled bool // Indicates if the PPU is disabled
 }
 
-func NewPPU(cpu impls.CPU) *PPU {
+func NewPPU(interrupts impls.Interrupts, scheduler *scheduler.Scheduler) *PPU {
 	ppu := &PPU{
-		cpu: cpu,
+		interrupts: interrupts,
+		scheduler:  scheduler,
 	}
 	ppu.Fetcher = NewFetcher(ppu)
 	ppu.Reset()
+	scheduler.Register(ppu)
 	return ppu
 }
 
@@ -118,6 +126,90 @@ func (ppu *PPU) GetPalleteColor(idx byte) byte {
 	return color
 }
 
+// register returns the LCD register at addr, or nil if there is none.
+func (ppu *PPU) register(addr uint16) *byte {
+	switch addr {
+	case 0xFF40:
+		return &ppu.LCDControl
+	case 0xFF41:
+		return &ppu.LCDStatus
+	case 0xFF42:
+		return &ppu.SCY
+	case 0xFF43:
+		return &ppu.SCX
+	case 0xFF44:
+		return &ppu.LY
+	case 0xFF45:
+		return &ppu.LYC
+	case 0xFF47:
+		return &ppu.BGP
+	case 0xFF48:
+		return &ppu.OBP0
+	case 0xFF49:
+		return &ppu.OBP1
+	case 0xFF4A:
+		return &ppu.WY
+	case 0xFF4B:
+		return &ppu.WX
+	default:
+		return nil
+	}
+}
+
+// Read8 reads the LCD registers at 0xFF40-0xFF45 and 0xFF47-0xFF4B.
+func (ppu *PPU) Read8(addr uint16) uint8 {
+	ppu.scheduler.Sync(ppu)
+	if addr == 0xFF44 && ppu.DoctorLY {
+		return 0x90
+	}
+	if r := ppu.register(addr); r != nil {
+		return *r
+	}
+	return 0xFF
+}
+
+func (ppu *PPU) Write8(addr uint16, data uint8) {
+	r := ppu.register(addr)
+	if r == nil || addr == 0xFF44 {
+		// LY is read-only
+		return
+	}
+	ppu.scheduler.Sync(ppu)
+	*r = data
+	ppu.scheduler.Wake(ppu)
+}
+
+// Sync steps the PPU a dot at a time through the elapsed T-cycles and
+// returns how long until it next changes mode or LY.
+func (ppu *PPU) Sync(elapsed uint64) uint64 {
+	for range elapsed {
+		ppu.Step()
+	}
+
+	if ppu.disabled != (ppu.LCDControl&LCDCDisplayEnable == 0) {
+		// The display was switched on or off and the next dot acts on it
+		return 1
+	}
+	if ppu.disabled {
+		return scheduler.Never
+	}
+	var last uint16
+	switch ppu.state {
+	case ppuStateOAMSearch:
+		last = 80
+	case ppuStateHBlank:
+		last = 339
+	case ppuStateVBlank:
+		last = 4560
+	default:
+		// Pixel transfer fetches from VRAM, so it has to keep up with writes
+		return scheduler.TCyclesPerMCycle
+	}
+	// The mode ends on the dot ticks reaches last. If the display was
+	// switched on mid-line ticks may be past it, and the count wraps around.
+	return uint64(last-ppu.ticks) + 1
+}
+
 func (ppu *PPU) Step() {
 	if ppu.disabled {
 		if ppu.LCDControl&LCDCDisplayEnable != 0 {
@@ -189,7 +281,7 @@ func (ppu *PPU) Step() {
 				ppu.FrameBufferA = [consts.FrameBufferSize]byte{}
 				ppu.HaveFrame = true
 				ppu.state = ppuStateVBlank
-				ppu.cpu.SetInterruptFlag(impls.VBlankInterrupt, true)
+				ppu.interrupts.SetInterruptFlag(impls.VBlankInterrupt, true)
 			} else {
 				slog.Debug("PPU: HBlank complete", "LY", ppu.LY, "ticks", ppu.ticks)
 				ppu.state = ppuStateOAMSearch
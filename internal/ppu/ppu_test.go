@@ -0,0 +1,86 @@
+package ppu_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/ppu"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+type fakeInterrupts struct {
+	flags impls.Interrupt
+}
+
+func (f *fakeInterrupts) SetInterruptFlag(flag impls.Interrupt, val bool) {
+	if val {
+		f.flags |= flag
+	}
+}
+
+// TestSyncMatchesStepping checks that a PPU run only at its next event
+// raises VBlank and reads back the same LY and STAT as one stepped every dot.
+func TestSyncMatchesStepping(t *testing.T) {
+	t.Parallel()
+
+	sched := scheduler.NewScheduler()
+	lazyInterrupts, eagerInterrupts := &fakeInterrupts{}, &fakeInterrupts{}
+	lazy := ppu.NewPPU(lazyInterrupts, sched)
+	eager := ppu.NewPPU(eagerInterrupts, scheduler.NewScheduler())
+	lazy.Write8(0xFF45, 0x05)
+	lazy.Write8(0xFF40, ppu.LCDCDisplayEnable)
+	eager.LYC = 0x05
+	eager.LCDControl = ppu.LCDCDisplayEnable
+
+	vblanks := 0
+	for cycle := range 3 * 70224 / scheduler.TCyclesPerMCycle {
+		sched.Tick()
+		for range scheduler.TCyclesPerMCycle {
+			eager.Step()
+		}
+		if lazyInterrupts.flags != eagerInterrupts.flags || lazy.HaveFrame != eager.HaveFrame {
+			t.Fatalf("M-cycle %d: interrupts = %v, frame = %v, want %v, %v",
+				cycle, lazyInterrupts.flags, lazy.HaveFrame, eagerInterrupts.flags, eager.HaveFrame)
+		}
+		if eager.HaveFrame {
+			vblanks++
+			lazy.HaveFrame, eager.HaveFrame = false, false
+			lazyInterrupts.flags, eagerInterrupts.flags = 0, 0
+		}
+		if cycle%97 != 0 {
+			continue
+		}
+		if got := lazy.Read8(0xFF44); got != eager.LY {
+			t.Fatalf("M-cycle %d: LY = %d, want %d", cycle, got, eager.LY)
+		}
+		if got := lazy.Read8(0xFF41); got != eager.LCDStatus {
+			t.Fatalf("M-cycle %d: STAT = 0x%02X, want 0x%02X", cycle, got, eager.LCDStatus)
+		}
+	}
+	if vblanks == 0 {
+		t.Error("no frame completed")
+	}
+}
+
+func TestLYReadOnly(t *testing.T) {
+	t.Parallel()
+
+	p := ppu.NewPPU(&fakeInterrupts{}, scheduler.NewScheduler())
+	p.Write8(0xFF44, 0x42)
+	if got := p.Read8(0xFF44); got != 0 {
+		t.Errorf("LY = 0x%02X after write, want 0x00", got)
+	}
+}
+
+func TestDoctorLY(t *testing.T) {
+	t.Parallel()
+
+	p := ppu.NewPPU(&fakeInterrupts{}, scheduler.NewScheduler())
+	p.DoctorLY = true
+	if got := p.Read8(0xFF44); got != 0x90 {
+		t.Errorf("LY = 0x%02X, want 0x90", got)
+	}
+	if p.LY != 0 {
+		t.Errorf("internal LY = %d, want 0", p.LY)
+	}
+}
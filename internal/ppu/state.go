@@ -0,0 +1,155 @@
+package ppu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+)
+
+// ppuSnapshot is the PPU state stored in save states.
+type ppuSnapshot struct {
+	VRAM         [consts.VRAMSize]byte
+	LCDControl   byte
+	LCDStatus    byte
+	SCX          byte
+	SCY          byte
+	LY           byte
+	LYC          byte
+	WX           byte
+	WY           byte
+	BGP          byte
+	OBP0         byte
+	OBP1         byte
+	OAM          [consts.OAMSize]byte
+	HaveFrame    bool
+	FrameBufferA [consts.FrameBufferSize]byte
+	FrameBufferB [consts.FrameBufferSize]byte
+	State        ppuState
+	Ticks        uint16
+	X            byte
+	Disabled     bool
+}
+
+// fetcherSnapshot is the fetcher state stored in save states.
+type fetcherSnapshot struct {
+	State       fetcherState
+	TileNum     uint8
+	TileIndex   byte
+	PixelBuffer [8]byte
+}
+
+// SaveState writes the PPU state, including the fetcher and its FIFO, to w.
+func (ppu *PPU) SaveState(w io.Writer) error {
+	ppu.scheduler.Sync(ppu)
+	snapshot := ppuSnapshot{
+		VRAM:         ppu.VRAM,
+		LCDControl:   ppu.LCDControl,
+		LCDStatus:    ppu.LCDStatus,
+		SCX:          ppu.SCX,
+		SCY:          ppu.SCY,
+		LY:           ppu.LY,
+		LYC:          ppu.LYC,
+		WX:           ppu.WX,
+		WY:           ppu.WY,
+		BGP:          ppu.BGP,
+		OBP0:         ppu.OBP0,
+		OBP1:         ppu.OBP1,
+		OAM:          ppu.OAM,
+		HaveFrame:    ppu.HaveFrame,
+		FrameBufferA: ppu.FrameBufferA,
+		FrameBufferB: ppu.FrameBufferB,
+		State:        ppu.state,
+		Ticks:        ppu.ticks,
+		X:            ppu.x,
+		Disabled:     ppu.disabled,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write PPU state: %w", err)
+	}
+	return ppu.Fetcher.SaveState(w)
+}
+
+// LoadState restores PPU state previously written with SaveState.
+func (ppu *PPU) LoadState(r io.Reader) error {
+	var snapshot ppuSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read PPU state: %w", err)
+	}
+	// VRAM and OAM are copied in place, as they are mapped into memory
+	ppu.VRAM = snapshot.VRAM
+	ppu.LCDControl = snapshot.LCDControl
+	ppu.LCDStatus = snapshot.LCDStatus
+	ppu.SCX = snapshot.SCX
+	ppu.SCY = snapshot.SCY
+	ppu.LY = snapshot.LY
+	ppu.LYC = snapshot.LYC
+	ppu.WX = snapshot.WX
+	ppu.WY = snapshot.WY
+	ppu.BGP = snapshot.BGP
+	ppu.OBP0 = snapshot.OBP0
+	ppu.OBP1 = snapshot.OBP1
+	ppu.OAM = snapshot.OAM
+	ppu.HaveFrame = snapshot.HaveFrame
+	ppu.FrameBufferA = snapshot.FrameBufferA
+	ppu.FrameBufferB = snapshot.FrameBufferB
+	ppu.state = snapshot.State
+	ppu.ticks = snapshot.Ticks
+	ppu.x = snapshot.X
+	ppu.disabled = snapshot.Disabled
+	return ppu.Fetcher.LoadState(r)
+}
+
+// SaveState writes the fetcher state, including its FIFO, to w.
+func (f *Fetcher) SaveState(w io.Writer) error {
+	snapshot := fetcherSnapshot{
+		State:       f.state,
+		TileNum:     f.tileNum,
+		TileIndex:   f.tileIndex,
+		PixelBuffer: f.pixelBuffer,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write fetcher state: %w", err)
+	}
+	return f.PixelFIFO.SaveState(w)
+}
+
+// LoadState restores fetcher state previously written with SaveState.
+func (f *Fetcher) LoadState(r io.Reader) error {
+	var snapshot fetcherSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read fetcher state: %w", err)
+	}
+	f.state = snapshot.State
+	f.tileNum = snapshot.TileNum
+	f.tileIndex = snapshot.TileIndex
+	f.pixelBuffer = snapshot.PixelBuffer
+	return f.PixelFIFO.LoadState(r)
+}
+
+// SaveState writes the pixels queued in the FIFO to w.
+func (f *FIFO) SaveState(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, uint8(f.size)); err != nil {
+		return fmt.Errorf("failed to write FIFO size: %w", err)
+	}
+	if _, err := w.Write(f.data[:f.size]); err != nil {
+		return fmt.Errorf("failed to write FIFO data: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores FIFO state previously written with SaveState.
+func (f *FIFO) LoadState(r io.Reader) error {
+	var size uint8
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return fmt.Errorf("failed to read FIFO size: %w", err)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return fmt.Errorf("failed to read FIFO data: %w", err)
+	}
+	f.size = int(size)
+	f.data = data
+	return nil
+}
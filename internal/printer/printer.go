@@ -0,0 +1,305 @@
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	CommandInit   byte = 0x01
+	CommandPrint  byte = 0x02
+	CommandData   byte = 0x04
+	CommandStatus byte = 0x0F
+)
+
+const (
+	StatusChecksumError byte = 1 << 0
+	StatusPrinting      byte = 1 << 1
+	StatusImageFull     byte = 1 << 2 // Image data is ready to print
+	StatusUnprocessed   byte = 1 << 3 // Image data has been received but not printed
+	StatusPacketError   byte = 1 << 4
+)
+
+const (
+	magic1 byte = 0x88
+	magic2 byte = 0x33
+	alive  byte = 0x81 // Printer ID, sent in place of the first status byte
+
+	width        = 160
+	tilesPerRow  = width / 8
+	bytesPerTile = 16
+	bufferSize   = 0x2000 // 8KB of printer RAM
+
+	// printingPolls is how many status packets report the printer as busy
+	// after a print command.
+	printingPolls = 4
+)
+
+type packetState uint8
+
+const (
+	stateMagic1 packetState = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthLow
+	stateLengthHigh
+	stateData
+	stateChecksumLow
+	stateChecksumHigh
+	stateAlive
+	stateStatus
+)
+
+// Printer is a Game Boy Printer connected to the link port. Every strip it
+// prints is saved as a PNG in its output directory.
+type Printer struct {
+	dir   string
+	index int // Number of the last image written
+
+	state      packetState
+	command    byte
+	compressed bool
+	length     uint16
+	packet     []byte
+	checksum   uint16 // Sum of the packet so far
+	received   uint16 // Checksum sent by the Game Boy
+
+	image    []byte // Decompressed tile data waiting to be printed
+	status   byte
+	printing int // Status packets left before printing finishes
+
+	// OnPrint, if set, is called with every printed image.
+	OnPrint func(img *image.Gray)
+}
+
+// NewPrinter creates a printer that saves images to dir.
+func NewPrinter(dir string) *Printer {
+	return &Printer{
+		dir: dir,
+	}
+}
+
+func (p *Printer) Exchange(out byte) byte {
+	switch p.state {
+	case stateMagic1:
+		if out == magic1 {
+			p.state = stateMagic2
+		}
+	case stateMagic2:
+		switch out {
+		case magic2:
+			p.state = stateCommand
+		case magic1:
+			// Still waiting for the second magic byte
+		default:
+			p.state = stateMagic1
+		}
+	case stateCommand:
+		p.command = out
+		p.checksum = uint16(out)
+		p.state = stateCompression
+	case stateCompression:
+		p.compressed = out&0x01 != 0
+		p.checksum += uint16(out)
+		p.state = stateLengthLow
+	case stateLengthLow:
+		p.length = uint16(out)
+		p.checksum += uint16(out)
+		p.state = stateLengthHigh
+	case stateLengthHigh:
+		p.length |= uint16(out) << 8
+		p.checksum += uint16(out)
+		p.packet = p.packet[:0]
+		p.state = stateData
+		if p.length == 0 {
+			p.state = stateChecksumLow
+		}
+	case stateData:
+		p.packet = append(p.packet, out)
+		p.checksum += uint16(out)
+		if len(p.packet) == int(p.length) {
+			p.state = stateChecksumLow
+		}
+	case stateChecksumLow:
+		p.received = uint16(out)
+		p.state = stateChecksumHigh
+	case stateChecksumHigh:
+		p.received |= uint16(out) << 8
+		p.handle()
+		p.state = stateAlive
+	case stateAlive:
+		p.state = stateStatus
+		return alive
+	case stateStatus:
+		p.state = stateMagic1
+		status := p.status
+		if p.printing > 0 {
+			p.printing--
+			if p.printing == 0 {
+				p.status &^= StatusPrinting
+			}
+		}
+		return status
+	}
+	return 0x00
+}
+
+func (p *Printer) Poll(_ byte) (byte, bool) {
+	// The printer never drives the clock
+	return 0, false
+}
+
+// Status returns the status byte the printer will report next.
+func (p *Printer) Status() byte {
+	return p.status
+}
+
+func (p *Printer) handle() {
+	if p.received != p.checksum {
+		p.status |= StatusChecksumError
+		return
+	}
+	p.status &^= StatusChecksumError | StatusPacketError
+
+	switch p.command {
+	case CommandInit:
+		p.image = p.image[:0]
+		p.status = 0
+		p.printing = 0
+	case CommandData:
+		if len(p.packet) == 0 {
+			// An empty data packet marks the end of the image
+			p.status |= StatusImageFull
+			return
+		}
+		data := p.packet
+		if p.compressed {
+			data = decompress(data)
+		}
+		if len(p.image)+len(data) > bufferSize {
+			p.status |= StatusPacketError
+			return
+		}
+		p.image = append(p.image, data...)
+		p.status |= StatusUnprocessed
+	case CommandPrint:
+		if len(p.packet) != 4 {
+			p.status |= StatusPacketError
+			return
+		}
+		sheets, palette, exposure := p.packet[0], p.packet[2], p.packet[3]
+		if sheets > 0 && len(p.image) > 0 {
+			p.print(render(p.image, palette, exposure))
+		}
+		p.image = p.image[:0]
+		p.status &^= StatusImageFull | StatusUnprocessed
+		p.status |= StatusPrinting
+		p.printing = printingPolls
+	case CommandStatus:
+	default:
+		p.status |= StatusPacketError
+	}
+}
+
+func (p *Printer) print(img *image.Gray) {
+	if p.OnPrint != nil {
+		p.OnPrint(img)
+	}
+	if p.dir == "" {
+		return
+	}
+	path, err := p.save(img)
+	if err != nil {
+		slog.Error("Failed to save printed image", "error", err)
+		return
+	}
+	slog.Info("Printed image", "path", path)
+}
+
+// save writes img to the next unused print-NNNN.png in the output directory.
+func (p *Printer) save(img *image.Gray) (string, error) {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create printer output directory: %w", err)
+	}
+	for {
+		p.index++
+		path := filepath.Join(p.dir, fmt.Sprintf("print-%04d.png", p.index))
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create image: %w", err)
+		}
+		if err := png.Encode(file, img); err != nil {
+			file.Close()
+			return "", fmt.Errorf("failed to encode image: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return "", fmt.Errorf("failed to write image: %w", err)
+		}
+		return path, nil
+	}
+}
+
+// decompress expands the printer's run-length encoding. A control byte with
+// the high bit set repeats the next byte (n&0x7F)+2 times, otherwise the next
+// n+1 bytes are copied as is.
+func decompress(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		control := data[i]
+		i++
+		if control&0x80 != 0 {
+			if i >= len(data) {
+				break
+			}
+			for range int(control&0x7F) + 2 {
+				out = append(out, data[i])
+			}
+			i++
+			continue
+		}
+		n := min(int(control)+1, len(data)-i)
+		out = append(out, data[i:i+n]...)
+		i += n
+	}
+	return out
+}
+
+// render converts tile data, 20 tiles per row, into an image. The palette
+// maps each color number to a shade like BGP, and the exposure darkens
+// (above 0x40) or lightens (below 0x40) every shade by up to 25%.
+func render(tiles []byte, palette, exposure byte) *image.Gray {
+	if palette == 0 {
+		// Some games leave the palette unset and expect the default
+		palette = 0xE4
+	}
+	darkness := 1 + (float64(exposure&0x7F)-0x40)/0x100
+
+	rows := len(tiles) / (tilesPerRow * bytesPerTile)
+	img := image.NewGray(image.Rect(0, 0, width, rows*8))
+	for tile := range rows * tilesPerRow {
+		data := tiles[tile*bytesPerTile : (tile+1)*bytesPerTile]
+		originX := (tile % tilesPerRow) * 8
+		originY := (tile / tilesPerRow) * 8
+		for y := range 8 {
+			low, high := data[y*2], data[y*2+1]
+			for x := range 8 {
+				bit := 7 - x
+				colorNumber := (low>>bit)&1 | ((high>>bit)&1)<<1
+				shade := (palette >> (colorNumber * 2)) & 0x03
+				level := min(float64(shade)/3*darkness, 1)
+				img.SetGray(originX+x, originY+y, color.Gray{Y: uint8(255 - level*255 + 0.5)})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,105 @@
+package printer_test
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/printer"
+)
+
+// send transfers a whole packet and returns the printer's two reply bytes.
+func send(p *printer.Printer, command byte, compressed bool, data []byte) (byte, byte) {
+	compression := byte(0)
+	if compressed {
+		compression = 1
+	}
+	packet := []byte{0x88, 0x33, command, compression, byte(len(data)), byte(len(data) >> 8)}
+	packet = append(packet, data...)
+	var checksum uint16
+	for _, b := range packet[2:] {
+		checksum += uint16(b)
+	}
+	packet = append(packet, byte(checksum), byte(checksum>>8))
+	for _, b := range packet {
+		p.Exchange(b)
+	}
+	return p.Exchange(0x00), p.Exchange(0x00)
+}
+
+func TestPrint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := printer.NewPrinter(dir)
+
+	if id, status := send(p, printer.CommandInit, false, nil); id != 0x81 || status != 0 {
+		t.Fatalf("init replied 0x%02X 0x%02X, want 0x81 0x00", id, status)
+	}
+
+	// Two tile rows where every pixel is color 3, compressed into runs
+	var compressed []byte
+	for remaining := 640; remaining > 0; {
+		run := min(remaining, 129)
+		compressed = append(compressed, 0x80|byte(run-2), 0xFF)
+		remaining -= run
+	}
+	_, status := send(p, printer.CommandData, true, compressed)
+	if status&printer.StatusUnprocessed == 0 {
+		t.Errorf("status after data = 0x%02X, want unprocessed data", status)
+	}
+	_, status = send(p, printer.CommandData, false, nil)
+	if status&printer.StatusImageFull == 0 {
+		t.Errorf("status after end of data = 0x%02X, want image data full", status)
+	}
+
+	var printed *image.Gray
+	p.OnPrint = func(img *image.Gray) {
+		printed = img
+	}
+	// One sheet, no margins, palette 0xE4, darkest exposure
+	_, status = send(p, printer.CommandPrint, false, []byte{0x01, 0x00, 0xE4, 0x7F})
+	if status&printer.StatusPrinting == 0 {
+		t.Errorf("status after print = 0x%02X, want printing", status)
+	}
+	if printed == nil {
+		t.Fatal("nothing was printed")
+	}
+	if bounds := printed.Bounds(); bounds.Dx() != 160 || bounds.Dy() != 16 {
+		t.Errorf("printed image is %dx%d, want 160x16", bounds.Dx(), bounds.Dy())
+	}
+	if y := printed.GrayAt(0, 0).Y; y != 0 {
+		t.Errorf("pixel = %d, want 0", y)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "print-0001.png"))
+	if err != nil {
+		t.Fatalf("image was not saved: %v", err)
+	}
+	defer file.Close()
+	if _, err := png.Decode(file); err != nil {
+		t.Errorf("saved image is not a PNG: %v", err)
+	}
+
+	for range 4 {
+		_, status = send(p, printer.CommandStatus, false, nil)
+	}
+	if status&printer.StatusPrinting != 0 {
+		t.Errorf("status = 0x%02X, want printing to have finished", status)
+	}
+}
+
+func TestChecksumError(t *testing.T) {
+	t.Parallel()
+
+	p := printer.NewPrinter("")
+	for _, b := range []byte{0x88, 0x33, printer.CommandStatus, 0x00, 0x00, 0x00, 0x00, 0x00} {
+		p.Exchange(b)
+	}
+	p.Exchange(0x00)
+	if status := p.Exchange(0x00); status&printer.StatusChecksumError == 0 {
+		t.Errorf("status = 0x%02X, want checksum error", status)
+	}
+}
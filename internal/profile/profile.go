@@ -0,0 +1,211 @@
+package profile
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/disasm"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+// Profiler counts the instructions run and M-cycles spent under each call
+// stack, to be written out as a pprof profile.
+type Profiler struct {
+	symbols *symbols.Table
+	start   time.Time
+	samples map[string]*sample // By the stack they were taken at, encoded by key
+	order   []*sample          // In the order they were first taken, to write them stably
+	key     []byte             // Scratch space for keys
+	cycles  uint64
+}
+
+// frame is an address on a call stack and the function it is in.
+type frame struct {
+	bank     int
+	addr     uint16
+	function string
+}
+
+type sample struct {
+	frames       []frame // Innermost first
+	instructions int64
+	cycles       int64
+}
+
+// New creates a profiler naming functions after the labels in table, which
+// may be nil.
+func New(table *symbols.Table) *Profiler {
+	return &Profiler{
+		symbols: table,
+		start:   time.Now(),
+		samples: make(map[string]*sample),
+	}
+}
+
+// Record counts an instruction at pc that took cycles, run with bank mapped
+// at 0x4000-0x7FFF and calls on the stack, innermost last.
+func (p *Profiler) Record(bank int, pc uint16, calls []cpu.Frame, cycles int) {
+	p.cycles += uint64(cycles)
+	p.key = append(p.key[:0], byte(bank))
+	p.key = binary.LittleEndian.AppendUint16(p.key, pc)
+	for i := len(calls) - 1; i >= 0; i-- {
+		p.key = binary.LittleEndian.AppendUint16(p.key, calls[i].Target)
+		p.key = binary.LittleEndian.AppendUint16(p.key, calls[i].Site)
+	}
+	s, ok := p.samples[string(p.key)]
+	if !ok {
+		s = p.sample(bank, pc, calls)
+		p.samples[string(p.key)] = s
+		p.order = append(p.order, s)
+	}
+	s.instructions++
+	s.cycles += int64(cycles)
+}
+
+// sample creates the sample for a stack seen for the first time.
+func (p *Profiler) sample(bank int, pc uint16, calls []cpu.Frame) *sample {
+	s := &sample{}
+	addr := pc
+	for i := len(calls) - 1; i >= -1; i-- {
+		// The function addr is in was called by the frame before it
+		f := frame{addr: addr}
+		if addr >= 0x4000 && addr < 0x8000 {
+			f.bank = bank
+		}
+		if i >= 0 {
+			f.function = p.function(bank, addr, &calls[i])
+			addr = calls[i].Site
+		} else {
+			f.function = p.function(bank, addr, nil)
+		}
+		s.frames = append(s.frames, f)
+	}
+	return s
+}
+
+// function names the function addr is in after the last global label
+// before it, or after the address it was called at if there is none, with
+// bank mapped at 0x4000-0x7FFF. Unlabelled functions are named as the
+// disassembler names them.
+func (p *Profiler) function(bank int, addr uint16, call *cpu.Frame) string {
+	if sym, ok := p.symbols.Nearest(bank, addr); ok {
+		name, _, _ := strings.Cut(sym.Name, ".")
+		return name
+	}
+	if call == nil {
+		return "Entry"
+	}
+	if name, ok := disasm.EntryPoint(call.Target); ok {
+		return name
+	}
+	if call.Target < 0x4000 || call.Target >= 0x8000 {
+		bank = 0
+	}
+	return fmt.Sprintf("Call_%03X_%04X", bank, call.Target)
+}
+
+// Write writes the profile in pprof's gzipped protocol buffer format, with
+// the instructions run and the M-cycles spent as sample values. Its duration
+// is the time the M-cycles take on a Game Boy.
+func (p *Profiler) Write(w io.Writer) error {
+	strs := map[string]uint64{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		i, ok := strs[s]
+		if !ok {
+			i = uint64(len(table))
+			strs[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+
+	var profile message
+	for _, sampleType := range []string{"instructions", "cycles"} {
+		profile.message(1, func(m *message) {
+			m.varint(1, str(sampleType))
+			m.varint(2, str("count"))
+		})
+	}
+
+	functions := make(map[string]uint64)
+	locations := make(map[frame]uint64)
+	var defs message // Locations and functions, written after the samples
+	for _, s := range p.order {
+		ids := make([]uint64, len(s.frames))
+		for i, f := range s.frames {
+			fn, ok := functions[f.function]
+			if !ok {
+				fn = uint64(len(functions) + 1)
+				functions[f.function] = fn
+				defs.message(5, func(m *message) {
+					m.varint(1, fn)
+					m.varint(2, str(f.function))
+					m.varint(3, str(f.function))
+				})
+			}
+			id, ok := locations[f]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[f] = id
+				defs.message(4, func(m *message) {
+					m.varint(1, id)
+					m.varint(3, uint64(f.bank)<<16|uint64(f.addr))
+					m.message(4, func(line *message) {
+						line.varint(1, fn)
+					})
+				})
+			}
+			ids[i] = id
+		}
+		profile.message(2, func(m *message) {
+			m.packed(1, ids)
+			m.packed(2, []uint64{uint64(s.instructions), uint64(s.cycles)})
+		})
+	}
+	profile.data = append(profile.data, defs.data...)
+
+	// The string table has to come after everything that adds to it
+	cyclesType := str("cycles")
+	countUnit := str("count")
+	for _, s := range table {
+		profile.string(6, s)
+	}
+	profile.varint(9, uint64(p.start.UnixNano()))
+	profile.varint(10, p.cycles*uint64(time.Second)/consts.CyclesPerSecond)
+	profile.message(11, func(m *message) {
+		m.varint(1, cyclesType)
+		m.varint(2, countUnit)
+	})
+	profile.varint(12, 1)
+	profile.varint(14, cyclesType)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(profile.data); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes the profile to a file at path.
+func (p *Profiler) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+	if err := p.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
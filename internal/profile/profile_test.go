@@ -0,0 +1,190 @@
+package profile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/profile"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+// fields splits a protocol buffer message into its fields, giving varints
+// as their value and length delimited fields as their bytes.
+func fields(t *testing.T, data []byte) []struct {
+	num   int
+	value uint64
+	bytes []byte
+} {
+	t.Helper()
+
+	var out []struct {
+		num   int
+		value uint64
+		bytes []byte
+	}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		data = data[n:]
+		value, n := binary.Uvarint(data)
+		data = data[n:]
+		field := struct {
+			num   int
+			value uint64
+			bytes []byte
+		}{num: int(tag >> 3), value: value}
+		switch tag & 7 {
+		case 0:
+		case 2:
+			field.bytes, data = data[:value], data[value:]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+func varints(data []byte) []uint64 {
+	var out []uint64
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		out = append(out, v)
+		data = data[n:]
+	}
+	return out
+}
+
+// decode returns the instructions and cycles of each stack in a profile,
+// written as function names innermost first, separated by semicolons.
+func decode(t *testing.T, profile []byte) map[string][2]uint64 {
+	t.Helper()
+
+	r, err := gzip.NewReader(bytes.NewReader(profile))
+	if err != nil {
+		t.Fatalf("profile isn't gzipped: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decompress profile: %v", err)
+	}
+
+	var (
+		table     []string
+		functions = make(map[uint64]uint64) // Name index by ID
+		locations = make(map[uint64]uint64) // Function ID by ID
+		samples   [][2][]uint64
+	)
+	for _, f := range fields(t, data) {
+		switch f.num {
+		case 2:
+			var sample [2][]uint64
+			for _, sf := range fields(t, f.bytes) {
+				sample[sf.num-1] = varints(sf.bytes)
+			}
+			samples = append(samples, sample)
+		case 4:
+			var id, function uint64
+			for _, lf := range fields(t, f.bytes) {
+				switch lf.num {
+				case 1:
+					id = lf.value
+				case 4:
+					function = fields(t, lf.bytes)[0].value
+				}
+			}
+			locations[id] = function
+		case 5:
+			parts := fields(t, f.bytes)
+			functions[parts[0].value] = parts[1].value
+		case 6:
+			table = append(table, string(f.bytes))
+		}
+	}
+
+	out := make(map[string][2]uint64)
+	for _, s := range samples {
+		names := make([]string, len(s[0]))
+		for i, loc := range s[0] {
+			names[i] = table[functions[locations[loc]]]
+		}
+		key := strings.Join(names, ";")
+		out[key] = [2]uint64{out[key][0] + s[1][0], out[key][1] + s[1][1]}
+	}
+	return out
+}
+
+func TestProfiler(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		pc     uint16
+		calls  []cpu.Frame
+		cycles int
+	}
+	update := cpu.Frame{Site: 0x0152, Target: 0x0160, Return: 0x0155, SP: 0xDFFE}
+	tests := []struct {
+		name    string
+		symbols string
+		records []record
+		want    map[string][2]uint64 // Instructions and cycles by stack
+	}{
+		{
+			name:    "labels",
+			symbols: "00:0150 Main\n00:0160 Update\n00:0165 Update.loop\n",
+			records: []record{
+				{pc: 0x0150, cycles: 2},
+				{pc: 0x0151, cycles: 3},
+				{pc: 0x0160, calls: []cpu.Frame{update}, cycles: 4},
+				{pc: 0x0166, calls: []cpu.Frame{update}, cycles: 1},
+			},
+			want: map[string][2]uint64{"Main": {2, 5}, "Update;Main": {2, 5}},
+		},
+		{
+			name: "no labels",
+			records: []record{
+				{pc: 0x0150, cycles: 1},
+				{pc: 0xFF80, calls: []cpu.Frame{{Site: 0x0150, Target: 0xFF80}}, cycles: 5},
+				{pc: 0x4001, calls: []cpu.Frame{{Site: 0x0150, Target: 0x4000}}, cycles: 2},
+				{pc: 0x0041, calls: []cpu.Frame{{Site: 0x0151, Target: 0x0040, Interrupt: true}}, cycles: 3},
+			},
+			want: map[string][2]uint64{
+				"Entry":                 {1, 1},
+				"Call_000_FF80;Entry":   {1, 5},
+				"Call_001_4000;Entry":   {1, 2},
+				"VBlankInterrupt;Entry": {1, 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			table, err := symbols.Parse(strings.NewReader(tt.symbols))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			p := profile.New(table)
+			for _, r := range tt.records {
+				p.Record(1, r.pc, r.calls, r.cycles)
+			}
+			var out bytes.Buffer
+			if err := p.Write(&out); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got := decode(t, out.Bytes())
+			if len(got) != len(tt.want) {
+				t.Errorf("profile has stacks %v, want %v", got, tt.want)
+			}
+			for stack, want := range tt.want {
+				if got[stack] != want {
+					t.Errorf("stack %s = %v, want %v", stack, got[stack], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package profile
+
+import "encoding/binary"
+
+// message encodes a protocol buffer message, for the few field types the
+// pprof format uses.
+type message struct {
+	data []byte
+}
+
+func (m *message) tag(field, wireType int) {
+	m.data = binary.AppendUvarint(m.data, uint64(field)<<3|uint64(wireType))
+}
+
+// varint encodes an integer field, leaving it out if it is zero.
+func (m *message) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	m.tag(field, 0)
+	m.data = binary.AppendUvarint(m.data, v)
+}
+
+func (m *message) bytes(field int, b []byte) {
+	m.tag(field, 2)
+	m.data = binary.AppendUvarint(m.data, uint64(len(b)))
+	m.data = append(m.data, b...)
+}
+
+func (m *message) string(field int, s string) {
+	m.bytes(field, []byte(s))
+}
+
+// packed encodes a repeated integer field.
+func (m *message) packed(field int, values []uint64) {
+	var inner []byte
+	for _, v := range values {
+		inner = binary.AppendUvarint(inner, v)
+	}
+	m.bytes(field, inner)
+}
+
+// message encodes an embedded message.
+func (m *message) message(field int, encode func(*message)) {
+	var inner message
+	encode(&inner)
+	m.bytes(field, inner.data)
+}
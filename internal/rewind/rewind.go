@@ -0,0 +1,146 @@
+package rewind
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// entry is a compressed snapshot. Keyframes hold the whole snapshot, other
+// entries hold it XORed against their keyframe so unchanged bytes compress
+// away.
+type entry struct {
+	data []byte
+	key  *entry // Keyframe the entry is a delta against, nil for keyframes
+}
+
+// Buffer is a ring buffer of machine snapshots for playing the game
+// backwards. Once full, pushing a snapshot drops the oldest one.
+type Buffer struct {
+	entries  []*entry
+	head     int // Index the next snapshot is pushed to
+	count    int
+	keyEvery int // Snapshots per keyframe
+
+	key      *entry // Keyframe new snapshots are deltas against
+	keyRaw   []byte // Uncompressed copy of key
+	keyCount int    // Snapshots pushed against key
+}
+
+// NewBuffer creates a buffer holding up to depth snapshots, with a keyframe
+// every keyEvery snapshots.
+func NewBuffer(depth, keyEvery int) *Buffer {
+	return &Buffer{
+		entries:  make([]*entry, depth),
+		keyEvery: max(keyEvery, 1),
+	}
+}
+
+// Len returns the number of snapshots in the buffer.
+func (b *Buffer) Len() int {
+	return b.count
+}
+
+// Reset drops every snapshot.
+func (b *Buffer) Reset() {
+	clear(b.entries)
+	b.head = 0
+	b.count = 0
+	b.key = nil
+	b.keyRaw = nil
+}
+
+// Push adds a snapshot, dropping the oldest one if the buffer is full.
+func (b *Buffer) Push(snapshot []byte) error {
+	if len(b.entries) == 0 {
+		return nil
+	}
+
+	var e *entry
+	if b.key == nil || b.keyCount >= b.keyEvery {
+		data, err := compress(snapshot)
+		if err != nil {
+			return err
+		}
+		e = &entry{data: data}
+		b.key = e
+		b.keyRaw = append(b.keyRaw[:0], snapshot...)
+		b.keyCount = 0
+	} else {
+		data, err := compress(xor(snapshot, b.keyRaw))
+		if err != nil {
+			return err
+		}
+		e = &entry{data: data, key: b.key}
+	}
+	b.keyCount++
+
+	b.entries[b.head] = e
+	b.head = (b.head + 1) % len(b.entries)
+	b.count = min(b.count+1, len(b.entries))
+	return nil
+}
+
+// Pop removes and returns the newest snapshot. It returns false when the
+// buffer is empty.
+func (b *Buffer) Pop() ([]byte, bool, error) {
+	if b.count == 0 {
+		return nil, false, nil
+	}
+	b.head = (b.head + len(b.entries) - 1) % len(b.entries)
+	e := b.entries[b.head]
+	b.entries[b.head] = nil
+	b.count--
+	// Start a fresh keyframe rather than track which one is now newest
+	b.key = nil
+
+	data, err := decompress(e.data)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.key == nil {
+		return data, true, nil
+	}
+	key, err := decompress(e.key.data)
+	if err != nil {
+		return nil, false, err
+	}
+	return xor(data, key), true, nil
+}
+
+// xor returns data XORed with key. Bytes of data past the end of key are
+// copied as is.
+func xor(data, key []byte) []byte {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = data[i]
+		if i < len(key) {
+			out[i] ^= key[i]
+		}
+	}
+	return out
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	out, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	return out, nil
+}
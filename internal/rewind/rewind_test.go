@@ -0,0 +1,81 @@
+package rewind_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/rewind"
+)
+
+func snapshot(n int) []byte {
+	data := make([]byte, 4096)
+	for i := range n {
+		data[i*7%len(data)] = byte(i)
+	}
+	return data
+}
+
+func TestPushPop(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		depth    int
+		keyEvery int
+		pushed   int
+	}{
+		{"keyframes only", 8, 1, 5},
+		{"deltas", 8, 4, 7},
+		{"wrapped", 4, 3, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := rewind.NewBuffer(tt.depth, tt.keyEvery)
+			for i := range tt.pushed {
+				if err := b.Push(snapshot(i)); err != nil {
+					t.Fatalf("Push() error = %v", err)
+				}
+			}
+			want := min(tt.pushed, tt.depth)
+			if b.Len() != want {
+				t.Fatalf("Len() = %d, want %d", b.Len(), want)
+			}
+			for i := tt.pushed - 1; i >= tt.pushed-want; i-- {
+				got, ok, err := b.Pop()
+				if err != nil || !ok {
+					t.Fatalf("Pop() = %v, %v", ok, err)
+				}
+				if !bytes.Equal(got, snapshot(i)) {
+					t.Fatalf("Pop() returned the wrong snapshot, want %d", i)
+				}
+			}
+			if _, ok, _ := b.Pop(); ok {
+				t.Error("Pop() on an empty buffer returned a snapshot")
+			}
+		})
+	}
+}
+
+func TestPushAfterPop(t *testing.T) {
+	t.Parallel()
+
+	b := rewind.NewBuffer(8, 4)
+	for i := range 3 {
+		_ = b.Push(snapshot(i))
+	}
+	_, _, _ = b.Pop()
+	_ = b.Push(snapshot(10))
+
+	for _, want := range []int{10, 1, 0} {
+		got, _, err := b.Pop()
+		if err != nil {
+			t.Fatalf("Pop() error = %v", err)
+		}
+		if !bytes.Equal(got, snapshot(want)) {
+			t.Fatalf("Pop() returned the wrong snapshot, want %d", want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import "math"
+
+// Never is returned by Component.Sync to sleep until woken with Wake.
+const Never = math.MaxUint64
+
+// TCyclesPerMCycle is the number of T-cycles (dots) in one M-cycle.
+const TCyclesPerMCycle = 4
+
+// Component is a device clocked by the scheduler. Rather than being stepped
+// every cycle, each component tells the scheduler when it next has work to
+// do and is only run then.
+type Component interface {
+	// Sync runs the component for elapsed T-cycles since it was last synced
+	// and returns how many T-cycles from now it next needs to run, or Never.
+	Sync(elapsed uint64) uint64
+}
+
+type registration struct {
+	component Component
+	last      uint64 // When the component was last synced
+	next      uint64 // When the component next needs to run
+}
+
+// Scheduler keeps time for the machine in T-cycles and runs each component
+// when its next event is due. The CPU advances it on every memory access and
+// internal cycle, so components observe the CPU at M-cycle granularity.
+type Scheduler struct {
+	now           uint64
+	registrations []registration
+	soonest       uint64 // Earliest next event of any component
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		soonest: Never,
+	}
+}
+
+// Register adds a component, which is first synced on the next tick.
+// Components run in the order they were registered when due together.
+func (s *Scheduler) Register(component Component) {
+	s.registrations = append(s.registrations, registration{
+		component: component,
+		last:      s.now,
+		next:      s.now,
+	})
+	s.soonest = s.now
+}
+
+// Now returns the number of T-cycles elapsed since the scheduler was
+// created.
+func (s *Scheduler) Now() uint64 {
+	return s.now
+}
+
+// Tick advances time by one M-cycle.
+func (s *Scheduler) Tick() {
+	s.Advance(TCyclesPerMCycle)
+}
+
+// Advance moves time forward and runs every component that became due.
+func (s *Scheduler) Advance(cycles uint64) {
+	s.now += cycles
+	if s.now < s.soonest {
+		return
+	}
+	s.soonest = Never
+	for i := range s.registrations {
+		r := &s.registrations[i]
+		if r.next <= s.now {
+			s.sync(r)
+		}
+		s.soonest = min(s.soonest, r.next)
+	}
+}
+
+// Sync brings a component up to the current time outside of its schedule,
+// for example before one of its registers is read.
+func (s *Scheduler) Sync(component Component) {
+	for i := range s.registrations {
+		r := &s.registrations[i]
+		if r.component == component {
+			s.sync(r)
+			s.soonest = min(s.soonest, r.next)
+			return
+		}
+	}
+}
+
+// Wake makes a component run on the next tick, for example after one of its
+// registers is written.
+func (s *Scheduler) Wake(component Component) {
+	for i := range s.registrations {
+		r := &s.registrations[i]
+		if r.component == component {
+			r.next = s.now
+			s.soonest = s.now
+			return
+		}
+	}
+}
+
+// Restart treats every component as synced up to now and due on the next
+// tick, after their state was replaced, for example by loading a save state.
+func (s *Scheduler) Restart() {
+	for i := range s.registrations {
+		s.registrations[i].last = s.now
+		s.registrations[i].next = s.now
+	}
+	s.soonest = s.now
+}
+
+func (s *Scheduler) sync(r *registration) {
+	wait := r.component.Sync(s.now - r.last)
+	r.last = s.now
+	if wait == Never {
+		r.next = Never
+	} else {
+		r.next = s.now + wait
+	}
+}
@@ -0,0 +1,74 @@
+package scheduler_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+// counter runs every period T-cycles and records how much time it saw.
+type counter struct {
+	period  uint64
+	elapsed uint64
+	syncs   int
+}
+
+func (c *counter) Sync(elapsed uint64) uint64 {
+	c.elapsed += elapsed
+	c.syncs++
+	return c.period
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		period    uint64
+		ticks     int
+		wantSyncs int
+	}{
+		{"every M-cycle", 4, 10, 11},
+		{"every 16 T-cycles", 16, 10, 3},
+		{"never", scheduler.Never, 10, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := scheduler.NewScheduler()
+			c := &counter{period: tt.period}
+			s.Register(c)
+			s.Advance(0)
+			for range tt.ticks {
+				s.Tick()
+			}
+			if c.syncs != tt.wantSyncs {
+				t.Errorf("syncs = %d, want %d", c.syncs, tt.wantSyncs)
+			}
+			s.Sync(c)
+			if c.elapsed != s.Now() {
+				t.Errorf("elapsed = %d, want %d", c.elapsed, s.Now())
+			}
+		})
+	}
+}
+
+func TestWake(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.NewScheduler()
+	c := &counter{period: scheduler.Never}
+	s.Register(c)
+	s.Tick()
+	s.Tick()
+	if c.syncs != 1 {
+		t.Fatalf("syncs = %d, want 1", c.syncs)
+	}
+	s.Wake(c)
+	s.Tick()
+	if c.syncs != 2 || c.elapsed != 12 {
+		t.Errorf("syncs = %d, elapsed = %d, want 2, 12", c.syncs, c.elapsed)
+	}
+}
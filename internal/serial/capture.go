@@ -0,0 +1,81 @@
+package serial
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+//nolint:gochecknoglobals
+var (
+	resultPassed = []byte("Passed")
+	resultFailed = []byte("Failed")
+)
+
+// Capture is a link peer that records every byte sent to it, which is how
+// test ROMs such as Blargg's report their results.
+type Capture struct {
+	writer io.Writer
+	closer io.Closer
+	output []byte
+
+	// OnResult, if set, is called once the output reports a test result.
+	OnResult func(passed bool)
+}
+
+func NewCapture(writer io.Writer) *Capture {
+	return &Capture{
+		writer: writer,
+	}
+}
+
+// OpenCapture captures serial output to the file at path, or to stdout if
+// path is "-".
+func OpenCapture(path string) (*Capture, error) {
+	if path == "-" {
+		return NewCapture(os.Stdout), nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serial output file: %w", err)
+	}
+	capture := NewCapture(file)
+	capture.closer = file
+	return capture, nil
+}
+
+func (c *Capture) Exchange(out byte) byte {
+	c.output = append(c.output, out)
+	if c.writer != nil {
+		_, _ = c.writer.Write([]byte{out})
+	}
+	if c.OnResult != nil {
+		switch {
+		case bytes.HasSuffix(c.output, resultPassed):
+			c.OnResult(true)
+		case bytes.HasSuffix(c.output, resultFailed):
+			c.OnResult(false)
+		}
+	}
+	// Nothing drives the data line, so it reads high
+	return 0xFF
+}
+
+func (c *Capture) Poll(_ byte) (byte, bool) {
+	// A capture never drives the clock
+	return 0, false
+}
+
+// Output returns every byte captured so far.
+func (c *Capture) Output() []byte {
+	return c.output
+}
+
+// Close closes the output file, if the capture opened one.
+func (c *Capture) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
@@ -0,0 +1,137 @@
+package serial
+
+import (
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+const (
+	// SCTransferStart is set to start a transfer and cleared on completion.
+	SCTransferStart byte = 1 << 7
+	// SCInternalClock selects the internal 8192 Hz clock instead of the peer's.
+	SCInternalClock byte = 1 << 0
+
+	// cyclesPerByte is the number of M-cycles the internal clock takes to
+	// shift 8 bits at 8192 Hz.
+	cyclesPerByte = 8 * 128
+)
+
+type Serial struct {
+	SB byte // SB, serial transfer data register
+	SC byte // SC, serial transfer control register
+
+	interrupts impls.Interrupts
+	peer       impls.SerialPeer
+	clock      impls.SerialClock // peer, if it needs to see every M-cycle
+	cycles     uint16            // M-cycles elapsed in the current internal clock transfer
+	scheduler  *scheduler.Scheduler
+}
+
+func NewSerial(interrupts impls.Interrupts, scheduler *scheduler.Scheduler) *Serial {
+	serial := &Serial{
+		interrupts: interrupts,
+		scheduler:  scheduler,
+	}
+	serial.Reset()
+	scheduler.Register(serial)
+	return serial
+}
+
+func (s *Serial) Reset() {
+	s.SB = 0x00
+	s.SC = 0x00
+	s.cycles = 0
+}
+
+// SetPeer connects a device to the link port, or disconnects it when nil.
+func (s *Serial) SetPeer(peer impls.SerialPeer) {
+	s.scheduler.Sync(s)
+	s.peer = peer
+	s.clock, _ = peer.(impls.SerialClock)
+	s.scheduler.Wake(s)
+}
+
+func (s *Serial) Read8(addr uint16) uint8 {
+	s.scheduler.Sync(s)
+	if addr == 0xFF01 {
+		return s.SB
+	}
+	// Unused SC bits read back as 1
+	return s.SC | 0x7E
+}
+
+func (s *Serial) Write8(addr uint16, data uint8) {
+	s.scheduler.Sync(s)
+	if addr == 0xFF01 {
+		s.SB = data
+	} else {
+		s.SC = data & (SCTransferStart | SCInternalClock)
+		s.cycles = 0
+	}
+	s.scheduler.Wake(s)
+}
+
+// Sync steps the serial port through the elapsed T-cycles, one M-cycle at a
+// time, and returns how long until the current transfer completes.
+func (s *Serial) Sync(elapsed uint64) uint64 {
+	for range elapsed / scheduler.TCyclesPerMCycle {
+		s.Step()
+	}
+
+	switch {
+	case s.clock != nil:
+		// The peer sees every M-cycle, transferring or not
+		return scheduler.TCyclesPerMCycle
+	case s.SC&SCTransferStart == 0:
+		return scheduler.Never
+	case s.SC&SCInternalClock != 0:
+		return uint64(cyclesPerByte-s.cycles) * scheduler.TCyclesPerMCycle
+	case s.peer != nil:
+		// The peer may clock the byte in on any M-cycle
+		return scheduler.TCyclesPerMCycle
+	default:
+		// Nothing will ever clock the transfer
+		return scheduler.Never
+	}
+}
+
+// Step advances the serial port by one M-cycle.
+func (s *Serial) Step() {
+	if s.clock != nil {
+		s.clock.Tick(s.SB)
+	}
+
+	if s.SC&SCTransferStart == 0 {
+		return
+	}
+
+	if s.SC&SCInternalClock == 0 {
+		// Wait for the peer to clock the transfer
+		if s.peer == nil {
+			return
+		}
+		in, ok := s.peer.Poll(s.SB)
+		if ok {
+			s.complete(in)
+		}
+		return
+	}
+
+	s.cycles++
+	if s.cycles < cyclesPerByte {
+		return
+	}
+	// With nothing connected the data line floats high
+	in := byte(0xFF)
+	if s.peer != nil {
+		in = s.peer.Exchange(s.SB)
+	}
+	s.complete(in)
+}
+
+func (s *Serial) complete(in byte) {
+	s.SB = in
+	s.SC &^= SCTransferStart
+	s.cycles = 0
+	s.interrupts.SetInterruptFlag(impls.SerialInterrupt, true)
+}
@@ -0,0 +1,83 @@
+package serial_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+)
+
+type fakeCPU struct {
+	interrupts impls.Interrupt
+}
+
+func (f *fakeCPU) SetInterruptFlag(flag impls.Interrupt, val bool) {
+	if val {
+		f.interrupts |= flag
+	}
+}
+
+type echoPeer struct {
+	sent []byte
+}
+
+func (p *echoPeer) Exchange(out byte) byte {
+	p.sent = append(p.sent, out)
+	return ^out
+}
+
+func (p *echoPeer) Poll(_ byte) (byte, bool) {
+	return 0, false
+}
+
+func TestInternalClockTransfer(t *testing.T) {
+	t.Parallel()
+
+	cpu := &fakeCPU{}
+	peer := &echoPeer{}
+	sched := scheduler.NewScheduler()
+	s := serial.NewSerial(cpu, sched)
+	s.SetPeer(peer)
+
+	s.Write8(0xFF01, 0x42)
+	s.Write8(0xFF02, 0x81)
+	for range 1023 {
+		sched.Tick()
+	}
+	if cpu.interrupts != 0 || s.Read8(0xFF02)&serial.SCTransferStart == 0 {
+		t.Fatalf("transfer completed early")
+	}
+	sched.Tick()
+	if cpu.interrupts != impls.SerialInterrupt {
+		t.Fatalf("interrupts = %v, want serial without reading the registers", cpu.interrupts)
+	}
+
+	if got := s.Read8(0xFF02); got != 0x7F {
+		t.Errorf("SC = 0x%02X, want 0x7F", got)
+	}
+	if got := s.Read8(0xFF01); got != 0xBD {
+		t.Errorf("SB = 0x%02X, want 0xBD", got)
+	}
+	if len(peer.sent) != 1 || peer.sent[0] != 0x42 {
+		t.Errorf("peer received %v, want [0x42]", peer.sent)
+	}
+	if cpu.interrupts != impls.SerialInterrupt {
+		t.Errorf("interrupts = %v, want serial", cpu.interrupts)
+	}
+}
+
+func TestExternalClockWaitsForPeer(t *testing.T) {
+	t.Parallel()
+
+	cpu := &fakeCPU{}
+	s := serial.NewSerial(cpu, scheduler.NewScheduler())
+
+	s.Write8(0xFF02, 0x80)
+	for range 10000 {
+		s.Step()
+	}
+	if s.Read8(0xFF02)&serial.SCTransferStart == 0 || cpu.interrupts != 0 {
+		t.Errorf("external clock transfer completed without a peer")
+	}
+}
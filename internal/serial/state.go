@@ -0,0 +1,41 @@
+package serial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// serialSnapshot is the serial port state stored in save states.
+type serialSnapshot struct {
+	SB     byte
+	SC     byte
+	Cycles uint16
+}
+
+// SaveState writes the serial port state to w. The connected peer is not
+// part of the state.
+func (s *Serial) SaveState(w io.Writer) error {
+	s.scheduler.Sync(s)
+	snapshot := serialSnapshot{
+		SB:     s.SB,
+		SC:     s.SC,
+		Cycles: s.cycles,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write serial state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores serial port state previously written with SaveState.
+func (s *Serial) LoadState(r io.Reader) error {
+	var snapshot serialSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read serial state: %w", err)
+	}
+	s.SB = snapshot.SB
+	s.SC = snapshot.SC
+	s.cycles = snapshot.Cycles
+	return nil
+}
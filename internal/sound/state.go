@@ -0,0 +1,23 @@
+package sound
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// SaveState writes the sound registers to w.
+func (s *Sound) SaveState(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, s); err != nil {
+		return fmt.Errorf("failed to write sound state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores sound registers previously written with SaveState.
+func (s *Sound) LoadState(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, s); err != nil {
+		return fmt.Errorf("failed to read sound state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package symbols
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSymbol = errors.New("invalid symbol")
+
+// Symbol is a label from a symbol file.
+type Symbol struct {
+	Bank int
+	Addr uint16
+	Name string
+}
+
+// Table holds the labels of a ROM, as listed in an RGBDS .sym file. Its
+// methods can be called on a nil Table, which has no symbols.
+type Table struct {
+	symbols []Symbol // Sorted by address, then bank
+	byName  map[string]Symbol
+}
+
+// Parse reads a symbol file made of lines like 01:4000 PlayerUpdate, with
+// comments starting with a semicolon.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{byName: make(map[string]Symbol)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		sym, err := parseSymbol(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%w on line %d: %s", ErrInvalidSymbol, line, strings.TrimSpace(text))
+		}
+		t.symbols = append(t.symbols, sym)
+		t.byName[sym.Name] = sym
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read symbols: %w", err)
+	}
+	sort.SliceStable(t.symbols, func(i, j int) bool {
+		a, b := t.symbols[i], t.symbols[j]
+		return a.Addr < b.Addr || a.Addr == b.Addr && a.Bank < b.Bank
+	})
+	return t, nil
+}
+
+func parseSymbol(fields []string) (Symbol, error) {
+	if len(fields) != 2 {
+		return Symbol{}, ErrInvalidSymbol
+	}
+	bankText, addrText, ok := strings.Cut(fields[0], ":")
+	if !ok {
+		return Symbol{}, ErrInvalidSymbol
+	}
+	bank, err := strconv.ParseUint(bankText, 16, 16)
+	if err != nil {
+		return Symbol{}, err
+	}
+	addr, err := strconv.ParseUint(addrText, 16, 16)
+	if err != nil {
+		return Symbol{}, err
+	}
+	return Symbol{Bank: int(bank), Addr: uint16(addr), Name: fields[1]}, nil
+}
+
+// Find returns path if it is set, and otherwise the path of the .sym file
+// next to the ROM at romPath if there is one. It returns an empty string if
+// there are no symbols to load.
+func Find(romPath, path string) string {
+	if path != "" || romPath == "" {
+		return path
+	}
+	path = strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sym"
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// Load reads the symbol file Find returns for romPath and path. It returns a
+// nil Table if there is none.
+func Load(romPath, path string) (*Table, error) {
+	path = Find(romPath, path)
+	if path == "" {
+		return nil, nil //nolint:nilnil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open symbols: %w", err)
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Symbols returns every symbol, ordered by address and bank.
+func (t *Table) Symbols() []Symbol {
+	if t == nil {
+		return nil
+	}
+	return append([]Symbol{}, t.symbols...)
+}
+
+// Find looks up a symbol by name.
+func (t *Table) Find(name string) (Symbol, bool) {
+	if t == nil {
+		return Symbol{}, false
+	}
+	sym, ok := t.byName[name]
+	return sym, ok
+}
+
+// Lookup returns the name of the symbol at addr, with bank the ROM bank
+// mapped at 0x4000-0x7FFF. Banks only matter for addresses in that range.
+func (t *Table) Lookup(bank int, addr uint16) (string, bool) {
+	sym, ok := t.Nearest(bank, addr)
+	if !ok || sym.Addr != addr {
+		return "", false
+	}
+	return sym.Name, true
+}
+
+// Nearest returns the last symbol at or before addr in the same region of
+// memory, such as the same ROM bank or WRAM.
+func (t *Table) Nearest(bank int, addr uint16) (Symbol, bool) {
+	if t == nil {
+		return Symbol{}, false
+	}
+	start := regionStart(addr)
+	after := sort.Search(len(t.symbols), func(i int) bool { return t.symbols[i].Addr > addr })
+	for i := after - 1; i >= 0 && t.symbols[i].Addr >= start; i-- {
+		if sym := t.symbols[i]; !inROMX(addr) || sym.Bank == bank {
+			return sym, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Format names addr after the nearest symbol, as in PlayerUpdate+3. It
+// returns an empty string if there is no symbol before addr.
+func (t *Table) Format(bank int, addr uint16) string {
+	sym, ok := t.Nearest(bank, addr)
+	switch {
+	case !ok:
+		return ""
+	case sym.Addr == addr:
+		return sym.Name
+	default:
+		return fmt.Sprintf("%s+%d", sym.Name, addr-sym.Addr)
+	}
+}
+
+func inROMX(addr uint16) bool {
+	return addr >= 0x4000 && addr < 0x8000
+}
+
+// regionStart returns the start of the region of the memory map addr is in.
+func regionStart(addr uint16) uint16 {
+	for _, start := range []uint16{0xFF80, 0xFF00, 0xFE00, 0xE000, 0xC000, 0xA000, 0x8000, 0x4000} {
+		if addr >= start {
+			return start
+		}
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package symbols_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+)
+
+const symFile = `; File generated by rgblink
+00:0150 Main
+00:0158 Main.loop
+01:4000 PlayerUpdate
+02:4000 EnemyUpdate
+00:c000 wPlayerX
+00:ff80 hDMARoutine
+`
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	table, err := symbols.Parse(strings.NewReader(symFile))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	tests := []struct {
+		bank int
+		addr uint16
+		want string
+	}{
+		{1, 0x0150, "Main"},
+		{1, 0x0153, "Main+3"},
+		{1, 0x015A, "Main.loop+2"},
+		{1, 0x4010, "PlayerUpdate+16"},
+		{2, 0x4000, "EnemyUpdate"},
+		{3, 0x4000, ""},
+		{1, 0x0100, ""},
+		{1, 0xC001, "wPlayerX+1"},
+		{1, 0xE000, ""},
+		{1, 0xFF80, "hDMARoutine"},
+	}
+	for _, tt := range tests {
+		if got := table.Format(tt.bank, tt.addr); got != tt.want {
+			t.Errorf("Format(%d, 0x%04X) = %q, want %q", tt.bank, tt.addr, got, tt.want)
+		}
+	}
+
+	if sym, ok := table.Find("PlayerUpdate"); !ok || sym.Bank != 1 || sym.Addr != 0x4000 {
+		t.Errorf("Find(PlayerUpdate) = %+v, %t", sym, ok)
+	}
+	var empty *symbols.Table
+	if got := empty.Format(0, 0x0150); got != "" {
+		t.Errorf("nil Table Format() = %q, want empty", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, line := range []string{"0150 Main", "00:zz50 Main", "00:0150"} {
+		if _, err := symbols.Parse(strings.NewReader(line)); !errors.Is(err, symbols.ErrInvalidSymbol) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidSymbol", line, err)
+		}
+	}
+}
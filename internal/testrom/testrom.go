@@ -0,0 +1,229 @@
+package testrom
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/ppu"
+	"github.com/USA-RedDragon/go-gb/internal/serial"
+)
+
+// Convention is the way a test ROM reports its result.
+type Convention string
+
+const (
+	// ConventionBlargg ROMs print "Passed" or "Failed" over the serial port.
+	ConventionBlargg Convention = "blargg"
+	// ConventionMooneye ROMs execute LD B,B with the Fibonacci numbers
+	// 3, 5, 8, 13, 21 and 34 in B, C, D, E, H and L if they passed.
+	ConventionMooneye Convention = "mooneye"
+	// ConventionAcid ROMs draw a picture that must match a reference image,
+	// then execute LD B,B.
+	ConventionAcid Convention = "acid"
+	// ConventionUnknown is reported for ROMs that never produced a result.
+	ConventionUnknown Convention = "unknown"
+)
+
+// Result is the outcome of running a single test ROM.
+type Result struct {
+	Path       string
+	Convention Convention
+	Passed     bool
+	Message    string // Why the test failed, or the error that stopped it
+	Error      bool   // Set if the ROM couldn't be run, rather than failing
+	Frames     int    // Frames emulated
+	Duration   time.Duration
+}
+
+//nolint:gochecknoglobals
+var fibonacci = [6]byte{3, 5, 8, 13, 21, 34}
+
+// Find returns every .gb file under dir, in lexical order.
+func Find(dir string) ([]string, error) {
+	var roms []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".gb") {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test ROMs: %w", err)
+	}
+	return roms, nil
+}
+
+// ReferencePath returns the path of the reference image for an acid test
+// ROM, which sits next to the ROM with a .png extension.
+func ReferencePath(rom string) string {
+	return strings.TrimSuffix(rom, filepath.Ext(rom)) + ".png"
+}
+
+// Run runs the ROM at path for at most the given number of frames and checks
+// its result. ROMs with a reference image are treated as acid tests, and any
+// others pass or fail by whichever of the blargg and mooneye conventions they
+// report a result with first.
+func Run(cfg *config.Config, path string, frames int) (result Result) {
+	result = Result{
+		Path:       path,
+		Convention: ConventionUnknown,
+	}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+		// The CPU panics on instructions it can't run
+		if r := recover(); r != nil {
+			result.Passed = false
+			result.Error = true
+			result.Message = fmt.Sprint(r)
+		}
+	}()
+
+	var reference *image.Gray
+	if _, err := os.Stat(ReferencePath(path)); err == nil {
+		reference, err = readReference(ReferencePath(path))
+		if err != nil {
+			result.Error = true
+			result.Message = err.Error()
+			return result
+		}
+		result.Convention = ConventionAcid
+	}
+
+	cart, err := cartridge.NewCartridge(path)
+	if err != nil {
+		result.Error = true
+		result.Message = fmt.Sprintf("failed to load cartridge: %v", err)
+		return result
+	}
+	gb, err := machine.New(cfg, cart)
+	if err != nil {
+		result.Error = true
+		result.Message = err.Error()
+		return result
+	}
+
+	var (
+		serialResult *bool
+		registers    *cpu.Registers
+	)
+	capture := serial.NewCapture(nil)
+	capture.OnResult = func(passed bool) {
+		if serialResult == nil {
+			serialResult = &passed
+		}
+	}
+	gb.Serial.SetPeer(capture)
+	gb.CPU.OnBreakpoint = func() {
+		if registers == nil {
+			r := gb.CPU.Registers()
+			registers = &r
+		}
+	}
+
+	var frame [consts.FrameBufferSize]byte
+	for result.Frames < frames {
+		frame = gb.RunUntilFrame()
+		result.Frames++
+
+		switch {
+		case reference != nil:
+			if registers != nil {
+				// Show the frame drawn after the breakpoint
+				frame = gb.RunUntilFrame()
+				result.Frames++
+				checkReference(&result, frame, reference)
+				return result
+			}
+		case serialResult != nil:
+			result.Convention = ConventionBlargg
+			result.Passed = *serialResult
+			if !result.Passed {
+				result.Message = strings.TrimSpace(string(capture.Output()))
+			}
+			return result
+		case registers != nil:
+			result.Convention = ConventionMooneye
+			checkRegisters(&result, *registers)
+			return result
+		}
+	}
+
+	if reference != nil {
+		checkReference(&result, frame, reference)
+		return result
+	}
+	result.Message = fmt.Sprintf("no result after %d frames", result.Frames)
+	if output := strings.TrimSpace(string(capture.Output())); output != "" {
+		result.Message += "\n" + output
+	}
+	return result
+}
+
+func checkRegisters(result *Result, r cpu.Registers) {
+	got := [6]byte{r.B, r.C, r.D, r.E, r.H, r.L}
+	result.Passed = got == fibonacci
+	if !result.Passed {
+		result.Message = fmt.Sprintf("B=%d C=%d D=%d E=%d H=%d L=%d", r.B, r.C, r.D, r.E, r.H, r.L)
+	}
+}
+
+func checkReference(result *Result, frame [consts.FrameBufferSize]byte, reference *image.Gray) {
+	img := ppu.FrameImage(frame)
+	if !reference.Bounds().Eq(img.Bounds()) {
+		result.Message = fmt.Sprintf("reference image is %dx%d, not %dx%d",
+			reference.Bounds().Dx(), reference.Bounds().Dy(), img.Bounds().Dx(), img.Bounds().Dy())
+		return
+	}
+	mismatched := 0
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			if shade(img.GrayAt(x, y).Y) != shade(reference.GrayAt(x, y).Y) {
+				mismatched++
+			}
+		}
+	}
+	result.Passed = mismatched == 0
+	if !result.Passed {
+		result.Message = fmt.Sprintf("%d pixels differ from the reference image", mismatched)
+	}
+}
+
+// shade quantizes a gray level to one of the four Game Boy shades, so
+// reference images with slightly different grays still match.
+func shade(level uint8) int {
+	return (255 - int(level) + 42) / 85
+}
+
+func readReference(path string) (*image.Gray, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference image: %w", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode reference image: %w", err)
+	}
+	gray := image.NewGray(img.Bounds().Sub(img.Bounds().Min))
+	for y := range gray.Bounds().Dy() {
+		for x := range gray.Bounds().Dx() {
+			gray.Set(x, y, img.At(img.Bounds().Min.X+x, img.Bounds().Min.Y+y))
+		}
+	}
+	return gray, nil
+}
@@ -0,0 +1,181 @@
+package testrom_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/testrom"
+)
+
+// lcdOn turns the LCD on with the background enabled.
+//
+//nolint:gochecknoglobals
+var lcdOn = []byte{0x3E, 0x91, 0xE0, 0x40} // LD A,0x91; LDH (0x40),A
+
+// loop jumps to itself forever.
+//
+//nolint:gochecknoglobals
+var loop = []byte{0x18, 0xFE} // JR -2
+
+func fibonacci(values ...byte) []byte {
+	program := append([]byte{}, lcdOn...)
+	for i, opcode := range []byte{0x06, 0x0E, 0x16, 0x1E, 0x26, 0x2E} { // LD B..L,n
+		program = append(program, opcode, values[i])
+	}
+	program = append(program, 0x40) // LD B,B
+	return append(program, loop...)
+}
+
+// serialPrint sends text over the serial port with the internal clock.
+func serialPrint(text string) []byte {
+	program := append([]byte{}, lcdOn...)
+	for _, char := range []byte(text) {
+		program = append(program,
+			0x3E, char, // LD A,char
+			0xE0, 0x01, // LDH (0x01),A
+			0x3E, 0x81, // LD A,0x81
+			0xE0, 0x02, // LDH (0x02),A
+			0xF0, 0x02, // LDH A,(0x02)
+			0xE6, 0x80, // AND 0x80
+			0x20, 0xFA, // JR NZ,-6
+		)
+	}
+	return append(program, loop...)
+}
+
+func writeROM(t *testing.T, dir, name string, program []byte) string {
+	t.Helper()
+
+	rom := make([]byte, 2*16384)
+	// Jump over the header to the program
+	copy(rom[0x100:], []byte{0x00, 0xC3, 0x50, 0x01}) // NOP; JP 0x0150
+	copy(rom[0x150:], program)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, rom, 0o600); err != nil {
+		t.Fatalf("failed to write ROM: %v", err)
+	}
+	return path
+}
+
+func writeReference(t *testing.T, rom string, level uint8) {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, 160, 144))
+	for i := range img.Pix {
+		img.Pix[i] = level
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode reference: %v", err)
+	}
+	if err := os.WriteFile(testrom.ReferencePath(rom), buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("failed to write reference: %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		program    []byte
+		reference  *color.Gray
+		convention testrom.Convention
+		passed     bool
+	}{
+		{
+			name:       "mooneye-pass.gb",
+			program:    fibonacci(3, 5, 8, 13, 21, 34),
+			convention: testrom.ConventionMooneye,
+			passed:     true,
+		},
+		{
+			name:       "mooneye-fail.gb",
+			program:    fibonacci(0x42, 0x42, 0x42, 0x42, 0x42, 0x42),
+			convention: testrom.ConventionMooneye,
+		},
+		{
+			name:       "blargg-pass.gb",
+			program:    serialPrint("Test\nPassed"),
+			convention: testrom.ConventionBlargg,
+			passed:     true,
+		},
+		{
+			name:       "blargg-fail.gb",
+			program:    serialPrint("Test\nFailed"),
+			convention: testrom.ConventionBlargg,
+		},
+		{
+			name:       "acid-pass.gb",
+			program:    fibonacci(0, 0, 0, 0, 0, 0),
+			reference:  &color.Gray{Y: 0xFF},
+			convention: testrom.ConventionAcid,
+			passed:     true,
+		},
+		{
+			name:       "acid-fail.gb",
+			program:    fibonacci(0, 0, 0, 0, 0, 0),
+			reference:  &color.Gray{Y: 0x00},
+			convention: testrom.ConventionAcid,
+		},
+		{
+			name:       "timeout.gb",
+			program:    append(append([]byte{}, lcdOn...), loop...),
+			convention: testrom.ConventionUnknown,
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rom := writeROM(t, dir, tt.name, tt.program)
+			if tt.reference != nil {
+				writeReference(t, rom, tt.reference.Y)
+			}
+			result := testrom.Run(&config.Config{LogLevel: config.LogLevelWarn}, rom, 60)
+			if result.Error {
+				t.Fatalf("Run() error: %s", result.Message)
+			}
+			if result.Convention != tt.convention {
+				t.Errorf("Convention = %s, want %s", result.Convention, tt.convention)
+			}
+			if result.Passed != tt.passed {
+				t.Errorf("Passed = %t, want %t (%s)", result.Passed, tt.passed, result.Message)
+			}
+		})
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	t.Parallel()
+
+	results := []testrom.Result{
+		{Path: "a.gb", Convention: testrom.ConventionBlargg, Passed: true},
+		{Path: "b.gb", Convention: testrom.ConventionMooneye, Message: "B=66 C=66 D=66 E=66 H=66 L=66"},
+		{Path: "c.gb", Convention: testrom.ConventionUnknown, Error: true, Message: "Unknown instruction"},
+	}
+	var buf bytes.Buffer
+	if err := testrom.WriteJUnit(&buf, "go-gb", results); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		`<testsuite name="go-gb" tests="3" failures="1" errors="1"`,
+		`<testcase name="b.gb" classname="mooneye"`,
+		`<failure message="B=66 C=66 D=66 E=66 H=66 L=66">`,
+		`<error message="Unknown instruction">`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package timer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// timerSnapshot is the timer state stored in save states.
+type timerSnapshot struct {
+	Counter uint16
+	TIMA    byte
+	TMA     byte
+	TAC     byte
+}
+
+// SaveState writes the timer state to w.
+func (t *Timer) SaveState(w io.Writer) error {
+	t.scheduler.Sync(t)
+	snapshot := timerSnapshot{
+		Counter: t.counter,
+		TIMA:    t.tima,
+		TMA:     t.tma,
+		TAC:     t.tac,
+	}
+	if err := binary.Write(w, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to write timer state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores timer state previously written with SaveState.
+func (t *Timer) LoadState(r io.Reader) error {
+	var snapshot timerSnapshot
+	if err := binary.Read(r, binary.LittleEndian, &snapshot); err != nil {
+		return fmt.Errorf("failed to read timer state: %w", err)
+	}
+	t.counter = snapshot.Counter
+	t.tima = snapshot.TIMA
+	t.tma = snapshot.TMA
+	t.tac = snapshot.TAC
+	return nil
+}
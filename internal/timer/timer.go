@@ -0,0 +1,130 @@
+package timer
+
+import (
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+)
+
+const (
+	// TACEnable starts TIMA counting.
+	TACEnable byte = 1 << 2
+	// TACClockMask selects how often TIMA counts.
+	TACClockMask byte = 0x03
+)
+
+// periods is how many T-cycles each TAC clock select takes per TIMA
+// increment. TIMA counts on the falling edge of bit 9, 3, 5 or 7 of the
+// system counter.
+//
+//nolint:gochecknoglobals
+var periods = [4]uint64{1024, 16, 64, 256}
+
+// Timer is the divider and timer, DIV/TIMA/TMA/TAC at 0xFF04-0xFF07.
+//
+// Rather than counting every cycle, the timer catches up whenever the
+// scheduler syncs it: before its registers are accessed and when TIMA is
+// due to overflow.
+type Timer struct {
+	counter uint16 // System counter, DIV is its upper byte
+	tima    byte   // TIMA, timer counter
+	tma     byte   // TMA, timer modulo
+	tac     byte   // TAC, timer control
+
+	interrupts impls.Interrupts
+	scheduler  *scheduler.Scheduler
+}
+
+func NewTimer(interrupts impls.Interrupts, scheduler *scheduler.Scheduler) *Timer {
+	timer := &Timer{
+		interrupts: interrupts,
+		scheduler:  scheduler,
+	}
+	timer.Reset()
+	scheduler.Register(timer)
+	return timer
+}
+
+func (t *Timer) Reset() {
+	t.counter = 0
+	t.tima = 0
+	t.tma = 0
+	t.tac = 0
+}
+
+func (t *Timer) Read8(addr uint16) uint8 {
+	t.scheduler.Sync(t)
+	switch addr {
+	case 0xFF04:
+		return byte(t.counter >> 8)
+	case 0xFF05:
+		return t.tima
+	case 0xFF06:
+		return t.tma
+	default:
+		// Unused TAC bits read back as 1
+		return t.tac | 0xF8
+	}
+}
+
+func (t *Timer) Write8(addr uint16, data uint8) {
+	t.scheduler.Sync(t)
+	switch addr {
+	case 0xFF04:
+		// Resetting the counter is a falling edge if the selected bit was set
+		if t.selectedBit() {
+			t.increment(1)
+		}
+		t.counter = 0
+	case 0xFF05:
+		t.tima = data
+	case 0xFF06:
+		t.tma = data
+	default:
+		before := t.selectedBit()
+		t.tac = data & (TACEnable | TACClockMask)
+		if before && !t.selectedBit() {
+			t.increment(1)
+		}
+	}
+	t.scheduler.Wake(t)
+}
+
+// Sync counts TIMA up for the time elapsed and returns how long until it
+// next overflows.
+func (t *Timer) Sync(elapsed uint64) uint64 {
+	if t.tac&TACEnable != 0 {
+		period := periods[t.tac&TACClockMask]
+		t.increment((uint64(t.counter)%period + elapsed) / period)
+	}
+	t.counter += uint16(elapsed)
+
+	if t.tac&TACEnable == 0 {
+		return scheduler.Never
+	}
+	period := periods[t.tac&TACClockMask]
+	return (0x100-uint64(t.tima))*period - uint64(t.counter)%period
+}
+
+// selectedBit reports whether the counter bit TIMA counts on is set, while
+// the timer is enabled.
+func (t *Timer) selectedBit() bool {
+	if t.tac&TACEnable == 0 {
+		return false
+	}
+	return uint64(t.counter)&(periods[t.tac&TACClockMask]/2) != 0
+}
+
+// increment counts TIMA up n times, reloading it from TMA and raising the
+// timer interrupt whenever it overflows.
+func (t *Timer) increment(n uint64) {
+	for n > 0 {
+		room := 0x100 - uint64(t.tima)
+		if n < room {
+			t.tima += byte(n)
+			return
+		}
+		n -= room
+		t.tima = t.tma
+		t.interrupts.SetInterruptFlag(impls.TimerInterrupt, true)
+	}
+}
@@ -0,0 +1,75 @@
+package timer_test
+
+import (
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/impls"
+	"github.com/USA-RedDragon/go-gb/internal/scheduler"
+	"github.com/USA-RedDragon/go-gb/internal/timer"
+)
+
+type fakeCPU struct {
+	interrupts int
+}
+
+func (f *fakeCPU) SetInterruptFlag(flag impls.Interrupt, val bool) {
+	if flag == impls.TimerInterrupt && val {
+		f.interrupts++
+	}
+}
+
+func TestDIV(t *testing.T) {
+	t.Parallel()
+
+	s := scheduler.NewScheduler()
+	tm := timer.NewTimer(&fakeCPU{}, s)
+	s.Advance(256 * 3)
+	if div := tm.Read8(0xFF04); div != 3 {
+		t.Errorf("DIV = %d, want 3", div)
+	}
+	tm.Write8(0xFF04, 0x42)
+	if div := tm.Read8(0xFF04); div != 0 {
+		t.Errorf("DIV after write = %d, want 0", div)
+	}
+}
+
+func TestTIMA(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		tac            byte
+		tima           byte
+		mcycles        int
+		wantTIMA       byte
+		wantInterrupts int
+	}{
+		{"disabled", 0x01, 0x00, 100, 0x00, 0},
+		{"every 16 T-cycles", 0x05, 0x00, 20, 0x05, 0},
+		{"every 1024 T-cycles", 0x04, 0x00, 1024, 0x04, 0},
+		{"overflow reloads TMA", 0x05, 0xFE, 8, 0x80, 1},
+		{"repeated overflows", 0x05, 0xFF, 4 + 4*0x80, 0x80, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cpu := &fakeCPU{}
+			s := scheduler.NewScheduler()
+			tm := timer.NewTimer(cpu, s)
+			tm.Write8(0xFF06, 0x80)
+			tm.Write8(0xFF05, tt.tima)
+			tm.Write8(0xFF07, tt.tac)
+			for range tt.mcycles {
+				s.Tick()
+			}
+			if cpu.interrupts != tt.wantInterrupts {
+				t.Errorf("interrupts = %d, want %d", cpu.interrupts, tt.wantInterrupts)
+			}
+			if tima := tm.Read8(0xFF05); tima != tt.wantTIMA {
+				t.Errorf("TIMA = 0x%02X, want 0x%02X", tima, tt.wantTIMA)
+			}
+		})
+	}
+}
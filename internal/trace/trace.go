@@ -0,0 +1,182 @@
+package trace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+)
+
+var ErrInvalidCondition = errors.New("trace condition must be pc=addr, pc=start-end or cycle=count")
+
+// Condition matches an instruction by its address or by the M-cycles run
+// before it.
+type Condition struct {
+	PC         bool   // Match the PC rather than the cycle count
+	Start, End uint16 // Inclusive range of PCs to match, if PC is set
+	Cycles     uint64 // Cycle count to match from, if PC isn't set
+}
+
+// ParseCondition parses a condition written as pc=0150, pc=C000-DFFF or
+// cycle=1000000, with addresses in hex. An empty string parses as nil, which
+// Writer takes as no condition.
+func ParseCondition(s string) (*Condition, error) {
+	if s == "" {
+		return nil, nil //nolint:nilnil
+	}
+	key, value, _ := strings.Cut(strings.ToLower(s), "=")
+	switch key {
+	case "pc":
+		start, end, isRange := strings.Cut(value, "-")
+		c := &Condition{PC: true}
+		var err error
+		if c.Start, err = parseAddress(start); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, s)
+		}
+		c.End = c.Start
+		if isRange {
+			if c.End, err = parseAddress(end); err != nil || c.End < c.Start {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, s)
+			}
+		}
+		return c, nil
+	case "cycle", "cycles":
+		cycles, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, s)
+		}
+		return &Condition{Cycles: cycles}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, s)
+}
+
+func parseAddress(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "$")
+	addr, err := strconv.ParseUint(s, 16, 16)
+	return uint16(addr), err
+}
+
+// Match reports whether the instruction at pc, run after the given number of
+// M-cycles, matches the condition.
+func (c *Condition) Match(pc uint16, cycles uint64) bool {
+	if c.PC {
+		return pc >= c.Start && pc <= c.End
+	}
+	return cycles >= c.Cycles
+}
+
+// Writer writes a line for every instruction run, in the format Gameboy
+// Doctor compares against reference logs:
+//
+//	A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02
+type Writer struct {
+	out    *bufio.Writer
+	closer io.Closer
+
+	start, stop *Condition
+	tracing     bool // Set once start has matched
+	done        bool // Set once stop has matched
+}
+
+// NewWriter traces to w from the first instruction start matches, or from
+// the first instruction if start is nil, until the first instruction after
+// that stop matches, if stop isn't nil.
+func NewWriter(w io.Writer, start, stop *Condition) *Writer {
+	return &Writer{
+		out:     bufio.NewWriter(w),
+		start:   start,
+		stop:    stop,
+		tracing: start == nil,
+	}
+}
+
+// Open traces to the file at path, or to stdout if path is "-", with the start
+// and stop conditions parsed by ParseCondition. It returns a nil Writer if
+// path is empty.
+func Open(path, start, stop string) (*Writer, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil
+	}
+	startCondition, err := ParseCondition(start)
+	if err != nil {
+		return nil, err
+	}
+	stopCondition, err := ParseCondition(stop)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := NewWriter(file, startCondition, stopCondition)
+	w.closer = file
+	return w, nil
+}
+
+// Create creates the file at path to trace to, or returns stdout, which
+// closing leaves open, if path is "-".
+func Create(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+	return file, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Trace writes the line for the instruction about to run at r.PC, whose first
+// bytes are pcmem, after the given number of M-cycles.
+func (w *Writer) Trace(r cpu.Registers, pcmem [4]byte, cycles uint64) {
+	if w.done {
+		return
+	}
+	if !w.tracing {
+		if !w.start.Match(r.PC, cycles) {
+			return
+		}
+		w.tracing = true
+	}
+	if w.stop != nil && w.stop.Match(r.PC, cycles) {
+		w.done = true
+		return
+	}
+	fmt.Fprintf(w.out, "A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X PCMEM:%02X,%02X,%02X,%02X\n",
+		r.A, r.F, r.B, r.C, r.D, r.E, r.H, r.L, r.SP, r.PC, pcmem[0], pcmem[1], pcmem[2], pcmem[3])
+}
+
+// Done reports whether the stop condition has matched.
+func (w *Writer) Done() bool {
+	return w.done
+}
+
+// Flush writes out any buffered lines.
+func (w *Writer) Flush() error {
+	return w.out.Flush()
+}
+
+// Close flushes the trace and closes its file, if Open created one.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
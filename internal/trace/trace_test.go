@@ -0,0 +1,69 @@
+package trace_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/trace"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	// Each instruction runs 4 M-cycles after the one before
+	pcs := []uint16{0x0100, 0x0101, 0x0150, 0x0152, 0x0154, 0x0150, 0x0152}
+	tests := []struct {
+		name        string
+		start, stop string
+		want        []uint16 // PCs traced
+	}{
+		{name: "everything", want: pcs},
+		{name: "from pc", start: "pc=0150", want: pcs[2:]},
+		{name: "pc range", start: "pc=0151-0154", stop: "pc=0150", want: []uint16{0x0152, 0x0154}},
+		{name: "cycle count", start: "cycle=8", stop: "cycles=16", want: []uint16{0x0150, 0x0152}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, err := trace.ParseCondition(tt.start)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) error = %v", tt.start, err)
+			}
+			stop, err := trace.ParseCondition(tt.stop)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) error = %v", tt.stop, err)
+			}
+			var out bytes.Buffer
+			w := trace.NewWriter(&out, start, stop)
+			for i, pc := range pcs {
+				w.Trace(cpu.Registers{A: 0x01, F: 0xB0, SP: 0xFFFE, PC: pc}, [4]byte{0x00, 0xC3, 0x50, 0x01}, uint64(i*4))
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			var want strings.Builder
+			for _, pc := range tt.want {
+				fmt.Fprintf(&want, "A:01 F:B0 B:00 C:00 D:00 E:00 H:00 L:00 SP:FFFE PC:%04X PCMEM:00,C3,50,01\n", pc)
+			}
+			if out.String() != want.String() {
+				t.Errorf("trace =\n%s\nwant\n%s", out.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestParseConditionInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, condition := range []string{"pc=zz", "pc=0200-0100", "cycle=-1", "frame=3", "0150"} {
+		if _, err := trace.ParseCondition(condition); !errors.Is(err, trace.ErrInvalidCondition) {
+			t.Errorf("ParseCondition(%q) error = %v, want ErrInvalidCondition", condition, err)
+		}
+	}
+}
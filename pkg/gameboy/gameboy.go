@@ -0,0 +1,359 @@
+// Package gameboy is an embeddable Game Boy emulator. It has no windowing or
+// audio dependencies: the caller drives it a step or a frame at a time, feeds
+// it the joypad state, and reads back the frame buffer and audio samples.
+package gameboy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/USA-RedDragon/go-gb/internal/cartridge"
+	"github.com/USA-RedDragon/go-gb/internal/config"
+	"github.com/USA-RedDragon/go-gb/internal/consts"
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/ppu"
+	"github.com/USA-RedDragon/go-gb/internal/profile"
+	"github.com/USA-RedDragon/go-gb/internal/symbols"
+	"github.com/USA-RedDragon/go-gb/internal/trace"
+)
+
+const (
+	ScreenWidth  = ppu.ScreenWidth
+	ScreenHeight = ppu.ScreenHeight
+	// FrameSize is the number of pixels in a frame.
+	FrameSize = ScreenWidth * ScreenHeight
+	// FrameRate is the number of frames the Game Boy shows a second.
+	FrameRate = consts.FrameRate
+	// SampleRate is the number of stereo audio samples produced a second.
+	SampleRate = 48000
+)
+
+var (
+	ErrNoROM        = errors.New("a ROM is required without a boot ROM")
+	ErrNotRecording = errors.New("no movie is being recorded")
+	ErrNotProfiling = errors.New("no profile is being taken")
+	// ErrInvalidWatchpoint is returned for a watchpoint missing its access or
+	// address.
+	ErrInvalidWatchpoint = errors.New("watchpoint must be an access and an address")
+)
+
+// Button is a bitmask of joypad buttons.
+type Button uint8
+
+const (
+	ButtonRight  Button = Button(input.ButtonRight)
+	ButtonLeft   Button = Button(input.ButtonLeft)
+	ButtonUp     Button = Button(input.ButtonUp)
+	ButtonDown   Button = Button(input.ButtonDown)
+	ButtonA      Button = Button(input.ButtonA)
+	ButtonB      Button = Button(input.ButtonB)
+	ButtonSelect Button = Button(input.ButtonSelect)
+	ButtonStart  Button = Button(input.ButtonStart)
+)
+
+// Registers is a snapshot of the CPU registers.
+type Registers struct {
+	A, F, B, C, D, E, H, L byte
+	SP, PC                 uint16
+	IME                    bool
+}
+
+// SerialPeer is the device at the other end of the link cable.
+//
+// A peer that also has a Tick(sb byte) method is called once per M-cycle
+// with the current contents of SB, for links that must run in lockstep.
+type SerialPeer interface {
+	// Exchange is called when this side, driving the clock, has shifted out a
+	// whole byte. It returns the byte shifted in from the peer.
+	Exchange(out byte) byte
+	// Poll is called while this side waits for the peer to drive the clock.
+	// Once the peer has clocked a whole byte it returns the byte shifted in
+	// and true, having taken out as the byte shifted out.
+	Poll(out byte) (byte, bool)
+}
+
+// Options configures a new GameBoy.
+type Options struct {
+	// BootROM is the 256 byte DMG boot ROM to start from. Without one the
+	// GameBoy starts in the state the boot ROM leaves behind.
+	BootROM []byte
+	// Trace logs every instruction run at debug level. It is slow.
+	Trace bool
+	// Symbols is the contents of an RGBDS .sym file for the ROM, naming
+	// addresses in traces and for Symbolize.
+	Symbols []byte
+}
+
+// GameBoy is an emulated DMG Game Boy with a cartridge inserted.
+type GameBoy struct {
+	machine  *machine.Machine
+	movie    *input.Movie      // Movie being recorded, if any
+	tracer   *trace.Writer     // Instruction trace being written, if any
+	profiler *profile.Profiler // Profile being taken, if any
+
+	audioSamples uint64 // Audio samples handed out by ReadAudio
+}
+
+// New powers on a Game Boy with the ROM inserted. The ROM may be empty to run
+// the boot ROM alone.
+func New(rom []byte, options Options) (*GameBoy, error) {
+	if len(rom) == 0 && options.BootROM == nil {
+		return nil, ErrNoROM
+	}
+	var (
+		cart *cartridge.Cartridge
+		err  error
+	)
+	if len(rom) > 0 {
+		cart, err = cartridge.ParseCartridge(rom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cartridge: %w", err)
+		}
+	}
+	cfg := &config.Config{LogLevel: config.LogLevelInfo, Speed: 1}
+	if options.Trace {
+		cfg.LogLevel = config.LogLevelDebug
+	}
+	m, err := machine.NewWithBIOS(cfg, cart, options.BootROM)
+	if err != nil {
+		return nil, err
+	}
+	if options.Symbols != nil {
+		table, err := symbols.Parse(bytes.NewReader(options.Symbols))
+		if err != nil {
+			return nil, err
+		}
+		m.SetSymbols(table)
+	}
+	return &GameBoy{
+		machine:      m,
+		audioSamples: samplesAt(m.Cycles()),
+	}, nil
+}
+
+// Reset powers the Game Boy off and on again.
+func (g *GameBoy) Reset() {
+	g.machine.Reset()
+}
+
+// Step runs a single CPU instruction and returns the M-cycles it took.
+func (g *GameBoy) Step() int {
+	return g.machine.Step()
+}
+
+// StepFrame runs until the PPU completes a frame, which Frame then returns.
+// A movie being played or recorded advances a frame. It stops early if a
+// watchpoint is hit, and the next call finishes the frame.
+func (g *GameBoy) StepFrame() {
+	g.machine.RunUntilFrame()
+}
+
+// Cycles returns the M-cycles run since the GameBoy was created.
+func (g *GameBoy) Cycles() uint64 {
+	return g.machine.Cycles()
+}
+
+// Frame returns the last completed frame, one byte per pixel row by row, from
+// 0 for white to 3 for black.
+func (g *GameBoy) Frame() [FrameSize]byte {
+	return g.machine.PPU.FrameBufferB
+}
+
+// FrameImage returns the last completed frame as a grayscale image.
+func (g *GameBoy) FrameImage() *image.Gray {
+	return ppu.FrameImage(g.machine.PPU.FrameBufferB)
+}
+
+// Buttons returns the buttons currently held.
+func (g *GameBoy) Buttons() Button {
+	return Button(g.machine.Input.Buttons())
+}
+
+// SetButtons sets the buttons held, releasing any others. It is ignored while
+// a movie is playing.
+func (g *GameBoy) SetButtons(buttons Button) {
+	if g.machine.Input.Playing() {
+		return
+	}
+	g.machine.Input.SetButtons(input.Button(buttons))
+}
+
+// ReadAudio fills dst with the interleaved left and right samples produced
+// since the last call, and returns the number of values written. Samples that
+// don't fit are kept for the next call.
+//
+// The APU isn't emulated yet, so the samples are silent, but they come at
+// the rate the hardware would produce them, so callers can already pace
+// their audio output against them.
+func (g *GameBoy) ReadAudio(dst []int16) int {
+	pending := samplesAt(g.machine.Cycles()) - g.audioSamples
+	n := min(pending, uint64(len(dst)/2))
+	clear(dst[:2*n])
+	g.audioSamples += n
+	return int(2 * n)
+}
+
+// samplesAt returns the number of stereo samples produced after the given
+// number of M-cycles.
+func samplesAt(cycles uint64) uint64 {
+	return cycles * SampleRate / consts.CyclesPerSecond
+}
+
+// Peek reads memory without taking any time. Unmapped addresses read 0xFF.
+func (g *GameBoy) Peek(addr uint16) byte {
+	return g.machine.Peek(addr)
+}
+
+// Poke writes memory without taking any time, as the CPU would.
+func (g *GameBoy) Poke(addr uint16, data byte) error {
+	return g.machine.Poke(addr, data)
+}
+
+// Symbolize names addr after the nearest label in Options.Symbols, as in
+// PlayerUpdate+3, or returns an empty string if there is none.
+func (g *GameBoy) Symbolize(addr uint16) string {
+	return g.machine.Symbolize(addr)
+}
+
+// Registers returns the CPU registers.
+func (g *GameBoy) Registers() Registers {
+	return Registers(g.machine.CPU.Registers())
+}
+
+// SetRegisters replaces the CPU registers.
+func (g *GameBoy) SetRegisters(registers Registers) {
+	g.machine.CPU.SetRegisters(cpu.Registers(registers))
+}
+
+// Halt stops the CPU until Resume is called. Stepping a halted GameBoy still
+// runs the other components.
+func (g *GameBoy) Halt() {
+	g.machine.CPU.Halt()
+}
+
+// Resume undoes Halt.
+func (g *GameBoy) Resume() {
+	g.machine.CPU.Resume()
+}
+
+// Halted reports whether the CPU is halted.
+func (g *GameBoy) Halted() bool {
+	return g.machine.CPU.IsHalted()
+}
+
+// SetSerialPeer connects a device to the link port, or disconnects it when
+// nil.
+func (g *GameBoy) SetSerialPeer(peer SerialPeer) {
+	g.machine.Serial.SetPeer(peer)
+}
+
+// SaveState writes a snapshot of the whole Game Boy to w. Loading it with
+// LoadState and running on is bit-identical to running on from here.
+func (g *GameBoy) SaveState(w io.Writer) error {
+	return g.machine.SaveState(w)
+}
+
+// LoadState restores a snapshot written by SaveState with the same ROM.
+func (g *GameBoy) LoadState(r io.Reader) error {
+	return g.machine.LoadState(r)
+}
+
+// PlayMovie reads a movie from r, resets to the point it was recorded from and
+// plays it back in place of SetButtons, a frame per StepFrame. A movie being
+// recorded starts from the same point, so it can be played back in turn.
+func (g *GameBoy) PlayMovie(r io.Reader) error {
+	movie, err := input.ReadMovie(r)
+	if err != nil {
+		return fmt.Errorf("failed to read movie: %w", err)
+	}
+	if err := g.machine.PlayMovie(movie); err != nil {
+		return err
+	}
+	if g.movie != nil {
+		g.movie.Header.StartState = movie.Header.StartState
+	}
+	return nil
+}
+
+// PlayInput plays back the buttons held for each frame in place of
+// SetButtons, from the next StepFrame on, without resetting.
+func (g *GameBoy) PlayInput(frames []Button) {
+	movie := &input.Movie{Frames: make([]input.Button, len(frames))}
+	for i, buttons := range frames {
+		movie.Frames[i] = input.Button(buttons)
+	}
+	g.machine.Input.Play(movie)
+}
+
+// PlayingMovie reports whether a movie or input is being played back.
+func (g *GameBoy) PlayingMovie() bool {
+	return g.machine.Input.Playing()
+}
+
+// RecordMovie resets the Game Boy and records its input from power-on until
+// StopRecording. To record a movie as it's played back, call RecordMovie
+// first, as it resets the Game Boy.
+func (g *GameBoy) RecordMovie() {
+	g.movie = g.machine.RecordMovie()
+}
+
+// Recording reports whether a movie is being recorded.
+func (g *GameBoy) Recording() bool {
+	return g.movie != nil
+}
+
+// StopRecording stops recording and writes the movie to w.
+func (g *GameBoy) StopRecording(w io.Writer) error {
+	if g.movie == nil {
+		return ErrNotRecording
+	}
+	g.machine.Input.StopRecording()
+	movie := g.movie
+	g.movie = nil
+	if err := movie.Write(w); err != nil {
+		return fmt.Errorf("failed to write movie: %w", err)
+	}
+	return nil
+}
+
+// StartTrace writes a line for every instruction run to w, in the format
+// Gameboy Doctor compares against reference logs, such as
+//
+//	A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02
+//
+// Tracing starts at the first instruction matching start and stops at the
+// first matching stop, either of which may be empty to trace from the start
+// or to the end. Conditions are written as pc=0150, pc=C000-DFFF or
+// cycle=1000000, with addresses in hex and M-cycles in decimal.
+func (g *GameBoy) StartTrace(w io.Writer, start, stop string) error {
+	startCondition, err := trace.ParseCondition(start)
+	if err != nil {
+		return err
+	}
+	stopCondition, err := trace.ParseCondition(stop)
+	if err != nil {
+		return err
+	}
+	if err := g.StopTrace(); err != nil {
+		return err
+	}
+	g.tracer = trace.NewWriter(w, startCondition, stopCondition)
+	g.machine.SetTracer(g.tracer)
+	return nil
+}
+
+// StopTrace stops tracing, writing out any lines buffered by StartTrace.
+func (g *GameBoy) StopTrace() error {
+	if g.tracer == nil {
+		return nil
+	}
+	err := g.tracer.Flush()
+	g.tracer = nil
+	g.machine.SetTracer(nil)
+	return err
+}
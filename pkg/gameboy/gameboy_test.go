@@ -0,0 +1,241 @@
+package gameboy_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/USA-RedDragon/go-gb/internal/input"
+	"github.com/USA-RedDragon/go-gb/pkg/gameboy"
+)
+
+// counterROM returns a ROM that turns the LCD on and increments 0xC000
+// forever.
+func counterROM() []byte {
+	rom := make([]byte, 2*16384)
+	// Jump over the header to the program
+	copy(rom[0x100:], []byte{0x00, 0xC3, 0x50, 0x01}) // NOP; JP 0x0150
+	copy(rom[0x150:], []byte{
+		0x3E, 0x91, // LD A,0x91
+		0xE0, 0x40, // LDH (0x40),A
+		0x21, 0x00, 0xC0, // LD HL,0xC000
+		0x34,       // INC (HL)
+		0x18, 0xFD, // JR -3
+	})
+	return rom
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rom     []byte
+		options gameboy.Options
+		wantErr bool
+	}{
+		{name: "ROM", rom: counterROM()},
+		{name: "no ROM", wantErr: true},
+		{name: "boot ROM only", options: gameboy.Options{BootROM: make([]byte, 256)}},
+		{name: "short boot ROM", rom: counterROM(), options: gameboy.Options{BootROM: make([]byte, 255)}, wantErr: true},
+		{name: "short ROM", rom: make([]byte, 0x100), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := gameboy.New(tt.rom, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStepAndPeek(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for range 7 {
+		gb.Step()
+	}
+	if got := gb.Registers().PC; got != 0x0157 {
+		t.Errorf("PC = 0x%04X, want 0x0157", got)
+	}
+	if got := gb.Peek(0xC000); got != 1 {
+		t.Errorf("Peek(0xC000) = %d, want 1", got)
+	}
+	if err := gb.Poke(0xC000, 0x42); err != nil {
+		t.Fatalf("Poke() error = %v", err)
+	}
+	if got := gb.Peek(0xC000); got != 0x42 {
+		t.Errorf("Peek(0xC000) after Poke = 0x%02X, want 0x42", got)
+	}
+}
+
+func TestReadAudio(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// A second of M-cycles
+	for gb.Cycles() < 1<<20 {
+		gb.Step()
+	}
+	buf := make([]int16, 4*gameboy.SampleRate)
+	if got := gb.ReadAudio(buf) / 2; got < gameboy.SampleRate-1 || got > gameboy.SampleRate+1 {
+		t.Errorf("ReadAudio() read %d samples, want %d", got, gameboy.SampleRate)
+	}
+	if got := gb.ReadAudio(buf); got != 0 {
+		t.Errorf("ReadAudio() read %d values again, want 0", got)
+	}
+}
+
+func TestSaveState(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	gb.StepFrame()
+	var state bytes.Buffer
+	if err := gb.SaveState(&state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	want := gb.Peek(0xC000)
+	gb.StepFrame()
+	if err := gb.LoadState(&state); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if got := gb.Peek(0xC000); got != want {
+		t.Errorf("Peek(0xC000) after LoadState = %d, want %d", got, want)
+	}
+}
+
+func TestStopRecording(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var movie bytes.Buffer
+	if err := gb.StopRecording(&movie); !errors.Is(err, gameboy.ErrNotRecording) {
+		t.Errorf("StopRecording() error = %v, want %v", err, gameboy.ErrNotRecording)
+	}
+	gb.RecordMovie()
+	gb.SetButtons(gameboy.ButtonA | gameboy.ButtonStart)
+	gb.StepFrame()
+	if err := gb.StopRecording(&movie); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	if err := gb.PlayMovie(&movie); err != nil {
+		t.Fatalf("PlayMovie() error = %v", err)
+	}
+	if !gb.PlayingMovie() {
+		t.Error("PlayingMovie() = false after PlayMovie")
+	}
+}
+
+func TestRecordWhilePlaying(t *testing.T) {
+	t.Parallel()
+
+	newGameBoy := func() *gameboy.GameBoy {
+		gb, err := gameboy.New(counterROM(), gameboy.Options{})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return gb
+	}
+	// play plays a movie for a few frames, recording it if record is set, and
+	// returns the counter and the recording
+	play := func(movie []byte, record bool) (byte, []byte) {
+		gb := newGameBoy()
+		if record {
+			gb.RecordMovie()
+		}
+		if err := gb.PlayMovie(bytes.NewReader(movie)); err != nil {
+			t.Fatalf("PlayMovie() error = %v", err)
+		}
+		for range 3 {
+			gb.StepFrame()
+		}
+		var recording bytes.Buffer
+		if record {
+			if err := gb.StopRecording(&recording); err != nil {
+				t.Fatalf("StopRecording() error = %v", err)
+			}
+		}
+		return gb.Peek(0xC000), recording.Bytes()
+	}
+
+	// A movie that starts from a state a few frames in
+	gb := newGameBoy()
+	for range 5 {
+		gb.StepFrame()
+	}
+	var state bytes.Buffer
+	if err := gb.SaveState(&state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	gb.RecordMovie()
+	gb.SetButtons(gameboy.ButtonA)
+	for range 3 {
+		gb.StepFrame()
+	}
+	var recorded bytes.Buffer
+	if err := gb.StopRecording(&recorded); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	movie, err := input.ReadMovie(&recorded)
+	if err != nil {
+		t.Fatalf("ReadMovie() error = %v", err)
+	}
+	movie.Header.StartState = state.Bytes()
+	var original bytes.Buffer
+	if err := movie.Write(&original); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want, _ := play(original.Bytes(), false)
+	got, rerecorded := play(original.Bytes(), true)
+	if got != want {
+		t.Errorf("counter while recording = %d, want %d", got, want)
+	}
+	if got, _ := play(rerecorded, false); got != want {
+		t.Errorf("counter playing the recording = %d, want %d", got, want)
+	}
+}
+
+func TestStartTrace(t *testing.T) {
+	t.Parallel()
+
+	gb, err := gameboy.New(counterROM(), gameboy.Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var out bytes.Buffer
+	if err := gb.StartTrace(&out, "pc=0150", "pc=0157"); err != nil {
+		t.Fatalf("StartTrace() error = %v", err)
+	}
+	for range 7 {
+		gb.Step()
+	}
+	if err := gb.StopTrace(); err != nil {
+		t.Fatalf("StopTrace() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	if want := "A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0150 PCMEM:3E,91,E0,40"; lines[0] != want {
+		t.Errorf("first line = %q, want %q", lines[0], want)
+	}
+}
@@ -0,0 +1,35 @@
+package gameboy
+
+import (
+	"io"
+
+	"github.com/USA-RedDragon/go-gb/internal/cpu"
+	"github.com/USA-RedDragon/go-gb/internal/profile"
+)
+
+// Frame is a call that hasn't returned, made by CALL, RST or an interrupt.
+type Frame = cpu.Frame
+
+// CallStack returns the calls that haven't returned, the innermost last.
+func (g *GameBoy) CallStack() []Frame {
+	return g.machine.CPU.CallStack()
+}
+
+// StartProfile starts counting the M-cycles spent in each function, named
+// after the labels in Options.Symbols or else after their addresses.
+func (g *GameBoy) StartProfile() {
+	g.profiler = profile.New(g.machine.Symbols())
+	g.machine.SetProfiler(g.profiler)
+}
+
+// StopProfile stops profiling and writes the profile to w in pprof's format,
+// for go tool pprof.
+func (g *GameBoy) StopProfile(w io.Writer) error {
+	if g.profiler == nil {
+		return ErrNotProfiling
+	}
+	profiler := g.profiler
+	g.profiler = nil
+	g.machine.SetProfiler(nil)
+	return profiler.Write(w)
+}
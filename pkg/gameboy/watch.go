@@ -0,0 +1,59 @@
+package gameboy
+
+import (
+	"github.com/USA-RedDragon/go-gb/internal/machine"
+	"github.com/USA-RedDragon/go-gb/internal/memory"
+)
+
+// Watchpoint stops the GameBoy when the CPU reads, writes or executes an
+// address in a range, optionally only if the byte matches a Condition.
+type Watchpoint = memory.Watchpoint
+
+// Condition is a predicate on the byte a watchpoint sees.
+type Condition = memory.Condition
+
+// Access is a bitmask of the kinds of memory access a watchpoint stops on.
+type Access = memory.Access
+
+const (
+	AccessRead    = memory.AccessRead
+	AccessWrite   = memory.AccessWrite
+	AccessExecute = memory.AccessExecute
+)
+
+// WatchHit describes the access that stopped the GameBoy at a watchpoint,
+// including the PC and disassembly of the instruction that made it.
+type WatchHit = machine.WatchHit
+
+// ParseWatchpoint parses a watchpoint written as an access such as rw, an
+// address or inclusive range such as C000-C0FF, and an optional condition
+// such as ==42, with numbers in hex.
+func ParseWatchpoint(access, addrs, condition string) (Watchpoint, error) {
+	return memory.ParseWatchpoint(access, addrs, condition)
+}
+
+// AddWatchpoint makes Step and StepFrame stop after an instruction that
+// matches the watchpoint. It returns the watchpoint with its ID assigned.
+func (g *GameBoy) AddWatchpoint(w Watchpoint) Watchpoint {
+	return g.machine.AddWatchpoint(w)
+}
+
+// RemoveWatchpoint removes the watchpoint with the given ID and reports
+// whether there was one.
+func (g *GameBoy) RemoveWatchpoint(id int) bool {
+	return g.machine.RemoveWatchpoint(id)
+}
+
+// Watchpoints returns the watchpoints set.
+func (g *GameBoy) Watchpoints() []Watchpoint {
+	return g.machine.Watchpoints()
+}
+
+// WatchHit returns the watchpoint hit by the last instruction run, if any.
+func (g *GameBoy) WatchHit() (WatchHit, bool) {
+	hit := g.machine.WatchHit()
+	if hit == nil {
+		return WatchHit{}, false
+	}
+	return *hit, true
+}